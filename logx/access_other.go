@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logx
+
+import "os"
+
+// canWrite checks the permission bits of dir, since there is no access(2).
+func canWrite(dir string) bool {
+	stat, err := os.Stat(dir)
+	return err == nil && stat.Mode().Perm()&0222 != 0
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package logx
+
+import "syscall"
+
+const _accessWrite = 0x2
+
+// canWrite checks if the process can write to dir by access(2).
+func canWrite(dir string) bool {
+	return syscall.Access(dir, _accessWrite) == nil
+}
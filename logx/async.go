@@ -0,0 +1,363 @@
+package logx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DropPolicyBlock blocks the logging until the queue has room.
+	DropPolicyBlock = "block"
+	// DropPolicyOldest drops the oldest entry in the queue.
+	DropPolicyOldest = "drop-oldest"
+	// DropPolicyNewest drops the entry being logged.
+	DropPolicyNewest = "drop-newest"
+)
+
+const (
+	_defaultAsyncBufferSize    = 256 * 1024
+	_defaultAsyncFlushInterval = time.Second
+	_defaultAsyncQueueSize     = 1024
+)
+
+var ErrAsyncClosed = errors.New("async writer is closed")
+
+// AsyncConfig represents asynchronous writing in toml/json, entries are
+// queued and written by a background goroutine with a buffer of
+// BufferSize (e.g. "256KiB"), which is flushed every FlushInterval
+// (e.g. "1s"). The queue holds at most QueueSize entries, and
+// DropPolicy decides what to do if it is full.
+type AsyncConfig struct {
+	BufferSize    string `toml:"buffer-size" json:"buffer-size"`
+	FlushInterval string `toml:"flush-interval" json:"flush-interval"`
+	QueueSize     int    `toml:"queue-size" json:"queue-size"`
+	DropPolicy    string `toml:"drop-policy" json:"drop-policy"`
+}
+
+func (a *AsyncConfig) bufferSize() (int, error) {
+	if a.BufferSize == "" {
+		return _defaultAsyncBufferSize, nil
+	}
+
+	size, err := parseSize(a.BufferSize)
+	if err != nil {
+		return 0, err
+	}
+
+	if size < 1 {
+		return 0, fmt.Errorf("invalid buffer size '%s'", a.BufferSize)
+	}
+
+	return int(size), nil
+}
+
+func (a *AsyncConfig) flushInterval() (time.Duration, error) {
+	if a.FlushInterval == "" {
+		return _defaultAsyncFlushInterval, nil
+	}
+
+	interval, err := time.ParseDuration(a.FlushInterval)
+	if err != nil {
+		return 0, err
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("invalid flush interval '%s'", a.FlushInterval)
+	}
+
+	return interval, nil
+}
+
+func (a *AsyncConfig) validate(key string) []error {
+	errs := []error{}
+	if _, err := a.bufferSize(); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".buffer-size", Err: err})
+	}
+
+	if _, err := a.flushInterval(); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".flush-interval", Err: err})
+	}
+
+	if a.QueueSize < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".queue-size", Err: fmt.Errorf("negative queue size '%d'", a.QueueSize)})
+	}
+
+	switch a.DropPolicy {
+	case "", DropPolicyBlock, DropPolicyOldest, DropPolicyNewest:
+	default:
+		errs = append(errs, &ConfigError{Key: key + ".drop-policy", Err: fmt.Errorf("invalid drop policy '%s'", a.DropPolicy)})
+	}
+
+	return errs
+}
+
+// AsyncStats represents the queue of an async output.
+type AsyncStats struct {
+	Name    string // the name of output, or "file" and "stdout"
+	Queued  int
+	Dropped uint64
+}
+
+// AsyncWriteSyncer is a zapcore.WriteSyncer writing in background,
+// Sync and Close return after all the queued entries are written.
+type AsyncWriteSyncer struct {
+	name   string
+	ws     zapcore.WriteSyncer
+	closer io.Closer
+	buf    *bufio.Writer
+	policy string
+	// direct writes each entry to ws without buffering, for the
+	// writers sending an entry as a message like syslog.
+	direct bool
+
+	mu       sync.Mutex
+	notFull  *sync.Cond
+	queue    [][]byte
+	size     int
+	closed   bool
+	writeErr error
+
+	wake     chan struct{}
+	flush    chan chan error
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+	closeErr error
+	dropped  uint64
+	meter    meter
+}
+
+// NewAsyncWriteSyncer starts writing to ws in background, the default
+// config is used if config is nil. Close does not close ws.
+func NewAsyncWriteSyncer(ws zapcore.WriteSyncer, config *AsyncConfig) (*AsyncWriteSyncer, error) {
+	if config == nil {
+		config = &AsyncConfig{}
+	}
+
+	bufferSize, err := config.bufferSize()
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := config.flushInterval()
+	if err != nil {
+		return nil, err
+	}
+
+	size := config.QueueSize
+	if size == 0 {
+		size = _defaultAsyncQueueSize
+	}
+
+	policy := config.DropPolicy
+	if policy == "" {
+		policy = DropPolicyBlock
+	}
+
+	w := &AsyncWriteSyncer{
+		ws:      ws,
+		buf:     bufio.NewWriterSize(ws, bufferSize),
+		policy:  policy,
+		size:    size,
+		wake:    make(chan struct{}, 1),
+		flush:   make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	w.notFull = sync.NewCond(&w.mu)
+
+	go w.run(interval)
+	return w, nil
+}
+
+// Write queues a copy of p, since zap reuses the buffer.
+func (w *AsyncWriteSyncer) Write(p []byte) (int, error) {
+	entry := append([]byte(nil), p...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for !w.closed && len(w.queue) >= w.size {
+		switch w.policy {
+		case DropPolicyNewest:
+			w.drop()
+			return len(p), nil
+		case DropPolicyOldest:
+			w.queue[0] = nil
+			w.queue = w.queue[1:]
+			w.drop()
+		default:
+			w.notFull.Wait()
+		}
+	}
+
+	if w.closed {
+		return 0, ErrAsyncClosed
+	}
+
+	w.queue = append(w.queue, entry)
+	if len(w.queue) == 1 {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync writes the queued entries and syncs the underlying writer.
+func (w *AsyncWriteSyncer) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case w.flush <- reply:
+		return <-reply
+	case <-w.stopped:
+		return nil
+	}
+}
+
+// Close writes the queued entries and stops the background goroutine,
+// the entries logged after Close are discarded with ErrAsyncClosed.
+func (w *AsyncWriteSyncer) Close() error {
+	w.once.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		close(w.done)
+		<-w.stopped
+
+		w.closeErr = w.writeErr
+		if w.closer != nil {
+			if err := w.closer.Close(); w.closeErr == nil {
+				w.closeErr = err
+			}
+		}
+	})
+
+	return w.closeErr
+}
+
+func (w *AsyncWriteSyncer) drop() {
+	atomic.AddUint64(&w.dropped, 1)
+	w.meter.addDropped(1)
+}
+
+// Stats returns the entries queued and dropped so far.
+func (w *AsyncWriteSyncer) Stats() AsyncStats {
+	w.mu.Lock()
+	queued := len(w.queue)
+	w.mu.Unlock()
+
+	return AsyncStats{Name: w.name, Queued: queued, Dropped: w.Dropped()}
+}
+
+// Dropped returns the number of entries dropped for the full queue.
+func (w *AsyncWriteSyncer) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *AsyncWriteSyncer) run(interval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.wake:
+			w.drain()
+		case <-ticker.C:
+			w.drain()
+			w.setErr(w.buf.Flush())
+		case reply := <-w.flush:
+			reply <- w.sync()
+		case <-w.done:
+			w.sync()
+			return
+		}
+	}
+}
+
+// drain moves the queued entries to the buffer, which is written to
+// the underlying writer when it is full.
+func (w *AsyncWriteSyncer) drain() {
+	w.mu.Lock()
+	entries := w.queue
+	w.queue = nil
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+
+	for _, entry := range entries {
+		var err error
+		if w.direct {
+			_, err = w.ws.Write(entry)
+		} else {
+			_, err = w.buf.Write(entry)
+		}
+
+		if err != nil {
+			w.setErr(err)
+		}
+	}
+}
+
+func (w *AsyncWriteSyncer) sync() error {
+	w.drain()
+	if err := w.buf.Flush(); err != nil {
+		w.setErr(err)
+		return err
+	}
+
+	return w.ws.Sync()
+}
+
+// setErr records the error, and resets the buffer which keeps failing
+// after an error.
+func (w *AsyncWriteSyncer) setErr(err error) {
+	if err == nil {
+		return
+	}
+
+	w.buf.Reset(w.ws)
+	w.mu.Lock()
+	w.writeErr = err
+	w.mu.Unlock()
+}
+
+// wrapAsync makes ws asynchronous if it is configured, and the returned
+// closer also closes closer. The bytes and drops are counted to Metrics
+// as sink.
+func (c *Config) wrapAsync(sink string, ws zapcore.WriteSyncer, closer io.Closer) (zapcore.WriteSyncer, io.Closer, error) {
+	direct := false
+	switch ws.(type) {
+	case *syslogConn, *journalConn:
+		direct = true
+	}
+
+	m := c.meter(sink)
+	if m.metrics != nil {
+		ws = &meteredWriteSyncer{WriteSyncer: ws, meter: m}
+	}
+
+	if c.Async == nil {
+		return ws, closer, nil
+	}
+
+	w, err := NewAsyncWriteSyncer(ws, c.Async)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.name, w.closer, w.meter, w.direct = sink, closer, m, direct
+	return w, w, nil
+}
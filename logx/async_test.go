@@ -0,0 +1,129 @@
+package logx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks the writes until gate is closed.
+type gatedWriter struct {
+	entered chan struct{}
+	gate    chan struct{}
+	once    sync.Once
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.entered) })
+	<-w.gate
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Sync() error {
+	return nil
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncDropPolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected string
+	}{
+		{DropPolicyNewest, "123"},
+		{DropPolicyOldest, "134"},
+	}
+
+	for _, test := range tests {
+		gw := &gatedWriter{entered: make(chan struct{}), gate: make(chan struct{})}
+		w, err := NewAsyncWriteSyncer(gw, &AsyncConfig{
+			BufferSize: "1B",
+			QueueSize:  2,
+			DropPolicy: test.policy,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the writer is busy with the first entry
+		w.Write([]byte("1"))
+		<-gw.entered
+		for _, entry := range []string{"2", "3", "4"} {
+			w.Write([]byte(entry))
+		}
+
+		close(gw.gate)
+		if err := w.Sync(); err != nil {
+			t.Fatal(err)
+		}
+
+		if gw.String() != test.expected {
+			t.Fatalf("Expected %s with %s, got: %s", test.expected, test.policy, gw.String())
+		}
+
+		if w.Dropped() != 1 {
+			t.Fatalf("Expected 1 dropped, got: %d", w.Dropped())
+		}
+
+		w.Close()
+		if _, err := w.Write([]byte("5")); err != ErrAsyncClosed {
+			t.Fatalf("Expected ErrAsyncClosed, got: %v", err)
+		}
+	}
+}
+
+func TestAsyncLogger(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewZapLogger(
+		WithFile(dir, "app.log", 10, 0, 0, false),
+		WithAsync("64KiB", 10*time.Millisecond, 16, DropPolicyBlock),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Info("flushed by interval")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+		if strings.Contains(string(content), "flushed by interval") {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected entry flushed, got: %s", content)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i := 0; i < 100; i++ {
+		lg.Logger().Info("flushed by close")
+	}
+
+	if stats := lg.AsyncStats(); len(stats) != 1 || stats[0].Name != "file" || stats[0].Dropped != 0 {
+		t.Fatalf("Expected the stats of file, got: %+v", stats)
+	}
+
+	if err := lg.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if n := strings.Count(string(content), "flushed by close"); n != 100 {
+		t.Fatalf("Expected 100 entries, got: %d", n)
+	}
+}
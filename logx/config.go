@@ -0,0 +1,176 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigError points to the bad key of configuration.
+type ConfigError struct {
+	Key string
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config key '%s': %v", e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfig loads configuration from TOML or JSON file by extension,
+// on top of NewDefaultConfig, then applies the environment overrides.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := NewDefaultConfig()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = decodeTomlConfig(data, config)
+	case ".json":
+		err = decodeJsonConfig(data, config)
+	default:
+		err = errors.New("unsupported config file, it must be .toml or .json")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := ApplyEnvConfig(config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func decodeTomlConfig(data []byte, config *Config) error {
+	md, err := toml.Decode(string(data), config)
+	if err != nil {
+		perr := toml.ParseError{}
+		if errors.As(err, &perr) && perr.LastKey != "" {
+			return &ConfigError{
+				Key: perr.LastKey,
+				Err: fmt.Errorf("line %d: %s", perr.Position.Line, perr.Message),
+			}
+		}
+
+		return err
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return &ConfigError{Key: undecoded[0].String(), Err: errors.New("unknown key")}
+	}
+
+	return nil
+}
+
+func decodeJsonConfig(data []byte, config *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(config)
+	if err == nil {
+		return nil
+	}
+
+	typeErr := &json.UnmarshalTypeError{}
+	if errors.As(err, &typeErr) {
+		return &ConfigError{
+			Key: typeErr.Field,
+			Err: fmt.Errorf("cannot use %s as %s", typeErr.Value, typeErr.Type),
+		}
+	}
+
+	syntaxErr := &json.SyntaxError{}
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+
+	// json: unknown field "name"
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		key, _ := strconv.Unquote(strings.TrimPrefix(msg, "json: unknown field "))
+		return &ConfigError{Key: key, Err: errors.New("unknown key")}
+	}
+
+	return err
+}
+
+type envSetter func(c *Config, value string) error
+
+func envBool(set func(c *Config, v bool)) envSetter {
+	return func(c *Config, value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		set(c, v)
+		return nil
+	}
+}
+
+func envFile(set func(f *FileConfig, v string)) envSetter {
+	return func(c *Config, value string) error {
+		if c.File == nil {
+			c.File = &FileConfig{}
+		}
+
+		set(c.File, value)
+		return nil
+	}
+}
+
+var _envConfig = []struct {
+	name string
+	set  envSetter
+}{
+	{"LOGX_LEVEL", func(c *Config, value string) error {
+		return c.Lvl.UnmarshalText([]byte(value))
+	}},
+	{"LOGX_FORMAT", func(c *Config, value string) error {
+		c.Format = value
+		return nil
+	}},
+	{"LOGX_OS_STDOUT", envBool(func(c *Config, v bool) { c.OsStdout = v })},
+	{"LOGX_FILE_PATH", envFile(func(f *FileConfig, v string) { f.Path = v })},
+	{"LOGX_FILE_NAME", envFile(func(f *FileConfig, v string) { f.Name = v })},
+	{"LOGX_DISABLE_TIMESTAMP", envBool(func(c *Config, v bool) { c.DisableTimestamp = v })},
+	{"LOGX_DEVELOPMENT", envBool(func(c *Config, v bool) { c.Development = v })},
+	{"LOGX_DISABLE_CALLER", envBool(func(c *Config, v bool) { c.DisableCaller = v })},
+	{"LOGX_DISABLE_STACKTRACE", envBool(func(c *Config, v bool) { c.DisableStacktrace = v })},
+}
+
+// ApplyEnvConfig overrides config by environment variables, e.g.
+// LOGX_LEVEL=info, LOGX_FORMAT=text, LOGX_OS_STDOUT=false.
+func ApplyEnvConfig(config *Config) error {
+	for _, env := range _envConfig {
+		value, ok := os.LookupEnv(env.name)
+		if !ok {
+			continue
+		}
+
+		if err := env.set(config, value); err != nil {
+			return &ConfigError{Key: env.name, Err: err}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.toml")
+	data := `
+level = "info"
+format = "text"
+
+[modules]
+storage = "debug"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LOGX_FORMAT", "json")
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Lvl != LvlInfo || config.Format != FormatJson || config.Modules["storage"] != LvlDebug {
+		t.Fatalf("Unexpected config: %+v", config)
+	}
+
+	path = filepath.Join(dir, "log.json")
+	if err := os.WriteFile(path, []byte(`{"file": {"maxsize": "big"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadConfig(path)
+	configErr := &ConfigError{}
+	if !errors.As(err, &configErr) || configErr.Key != "file.maxsize" {
+		t.Fatalf("Expected error of key file.maxsize, got: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"levle": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadConfig(path)
+	if !errors.As(err, &configErr) || configErr.Key != "levle" {
+		t.Fatalf("Expected error of key levle, got: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := InitZapLogger(&Config{
+		Lvl:    LvlInfo,
+		Format: FormatJson,
+		File:   NewFileConfig(dir, "a.log", 1, 1, 1, false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := lg.Logger().With(zap.String("app", "pkg"))
+
+	path := filepath.Join(dir, "log.json")
+	data := `{"level": "debug", "format": "text", "os-stdout": false, "file": {"path": "` + dir + `", "name": "b.log"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lg.WatchConfig(ctx, path, 10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	// make sure the modification is noticed
+	future := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for lg.GetLevel() != LvlDebug {
+		select {
+		case err := <-errs:
+			t.Fatal(err)
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	logger.Debug("reloaded")
+	lg.Sync()
+
+	b, err := os.ReadFile(filepath.Join(dir, "b.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), "message=reloaded app=pkg") {
+		t.Fatalf("Unexpected output after reload: %s", b)
+	}
+
+	// an entry checked before reload is not written to the closed file
+	ce := logger.Check(zap.InfoLevel, "checked")
+	if err := lg.Reload(&Config{Lvl: LvlInfo, Format: FormatJson, File: NewFileConfig(dir, "c.log", 1, 1, 1, false)}); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Remove(filepath.Join(dir, "b.log"))
+	ce.ErrorOutput = zapcore.AddSync(io.Discard)
+	ce.Write()
+	if _, err := os.Stat(filepath.Join(dir, "b.log")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no reopened b.log, got: %v", err)
+	}
+
+	// the default interval is used rather than panicking
+	lg.WatchConfig(ctx, path, 0, nil)
+}
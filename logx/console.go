@@ -0,0 +1,235 @@
+package logx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+const (
+	_colorRed     = "\x1b[31m"
+	_colorYellow  = "\x1b[33m"
+	_colorBlue    = "\x1b[34m"
+	_colorMagenta = "\x1b[35m"
+	_colorBold    = "\x1b[1m"
+	_colorDim     = "\x1b[90m"
+	_colorReset   = "\x1b[0m"
+
+	// the width of caller column, longer callers are not cut
+	_callerWidth = 24
+)
+
+var _devPool = buffer.NewPool()
+
+func levelColor(lvl zapcore.Level) string {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return _colorMagenta
+	case zapcore.InfoLevel:
+		return _colorBlue
+	case zapcore.WarnLevel:
+		return _colorYellow
+	default:
+		return _colorRed
+	}
+}
+
+// colorEnabled returns if mode enables colors of out, which is nil for
+// files and other sinks never colored. "auto" (or empty) enables them
+// if out is a terminal and NO_COLOR is not set.
+func colorEnabled(mode string, out *os.File) bool {
+	if out == nil {
+		return false
+	}
+
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	info, err := out.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func isValidColor(mode string) bool {
+	switch mode {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		return true
+	default:
+		return false
+	}
+}
+
+// devEncoder encodes entries for reading in terminal, the columns of
+// time, level and caller are aligned, the fields follow the message in
+// logfmt, and the stacks and verbose errors are written below.
+type devEncoder struct {
+	*logfmtEncoder // encoder of fields
+	config         zapcore.EncoderConfig
+	color          bool
+}
+
+// NewDevEncoder creates a zapcore.Encoder for development console.
+func NewDevEncoder(config zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	fieldsConfig := zapcore.EncoderConfig{
+		EncodeTime:     config.EncodeTime,
+		EncodeDuration: config.EncodeDuration,
+		LineEnding:     "\n",
+	}
+
+	return &devEncoder{
+		logfmtEncoder: NewLogfmtEncoder(fieldsConfig).(*logfmtEncoder),
+		config:        config,
+		color:         color,
+	}
+}
+
+func (e *devEncoder) Clone() zapcore.Encoder {
+	return &devEncoder{
+		logfmtEncoder: e.logfmtEncoder.Clone().(*logfmtEncoder),
+		config:        e.config,
+		color:         e.color,
+	}
+}
+
+func (e *devEncoder) colored(buf *buffer.Buffer, color, s string) {
+	if !e.color {
+		buf.AppendString(s)
+		return
+	}
+
+	buf.AppendString(color)
+	buf.AppendString(s)
+	buf.AppendString(_colorReset)
+}
+
+func (e *devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	fields, blocks := splitErrorBlocks(fields)
+	encoded, err := e.logfmtEncoder.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	buf := _devPool.Get()
+	if e.config.TimeKey != "" && e.config.EncodeTime != nil {
+		a := &logfmtArray{enc: e.logfmtEncoder}
+		e.config.EncodeTime(ent.Time, a)
+		e.colored(buf, _colorDim, a.join())
+		buf.AppendByte(' ')
+	}
+
+	e.colored(buf, levelColor(ent.Level), fmt.Sprintf("%-5s", ent.Level.CapitalString()))
+	buf.AppendByte(' ')
+
+	if ent.Caller.Defined && e.config.CallerKey != "" {
+		e.colored(buf, _colorDim, fmt.Sprintf("%-*s", _callerWidth, ent.Caller.TrimmedPath()))
+		buf.AppendByte(' ')
+	}
+
+	if ent.LoggerName != "" && e.config.NameKey != "" {
+		e.colored(buf, _colorBold, ent.LoggerName+":")
+		buf.AppendByte(' ')
+	}
+
+	buf.AppendString(ent.Message)
+	if line := bytes.TrimRight(encoded.Bytes(), "\n"); len(line) > 0 {
+		buf.AppendString("  ")
+		buf.Write(line)
+	}
+	buf.AppendByte('\n')
+
+	for _, block := range blocks {
+		e.colored(buf, _colorRed, "  "+block.key+":")
+		buf.AppendByte('\n')
+		e.appendIndented(buf, block.text, "")
+	}
+
+	if ent.Stack != "" && e.config.StacktraceKey != "" {
+		e.colored(buf, _colorDim, "  stack:")
+		buf.AppendByte('\n')
+		e.appendIndented(buf, ent.Stack, _colorDim)
+	}
+
+	return buf, nil
+}
+
+// appendIndented writes the lines of text indented, the lines already
+// indented by tab, e.g. the files of stack, are colored with color if any.
+func (e *devEncoder) appendIndented(buf *buffer.Buffer, text, color string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		buf.AppendString("    ")
+		if !strings.HasPrefix(line, "\t") {
+			buf.AppendString(line)
+		} else if color != "" {
+			e.colored(buf, color, "  "+strings.TrimLeft(line, "\t"))
+		} else {
+			buf.AppendString("  " + strings.TrimLeft(line, "\t"))
+		}
+		buf.AppendByte('\n')
+	}
+}
+
+type errorBlock struct {
+	key  string
+	text string
+}
+
+// splitErrorBlocks writes the first line of errors in fields, and the
+// errors of multiple lines or with verbose form are returned as blocks.
+func splitErrorBlocks(fields []zapcore.Field) ([]zapcore.Field, []errorBlock) {
+	var blocks []errorBlock
+	converted := fields
+	copied := false
+	for i, field := range fields {
+		var err error
+		verbose := true
+		switch v := field.Interface.(type) {
+		case errorObject:
+			err, verbose = v.err, v.verbose
+		case error:
+			if field.Type == zapcore.ErrorType {
+				err = v
+			}
+		}
+
+		if err == nil {
+			continue
+		}
+
+		msg := err.Error()
+		text := msg
+		if _, ok := err.(fmt.Formatter); ok && verbose {
+			text = fmt.Sprintf("%+v", err)
+		}
+
+		if !copied {
+			converted, copied = append([]zapcore.Field(nil), fields...), true
+		}
+
+		first, _, multiline := strings.Cut(msg, "\n")
+		converted[i] = zap.String(field.Key, first)
+		if multiline || text != msg {
+			blocks = append(blocks, errorBlock{key: field.Key, text: text})
+		}
+	}
+
+	return converted, blocks
+}
@@ -0,0 +1,117 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type verboseError struct{}
+
+func (verboseError) Error() string {
+	return "boom"
+}
+
+func (e verboseError) Format(s fmt.State, verb rune) {
+	if s.Flag('+') {
+		fmt.Fprint(s, "boom\nmain.run\n\tmain.go:10")
+		return
+	}
+
+	fmt.Fprint(s, e.Error())
+}
+
+func TestDevEncoder(t *testing.T) {
+	config := &Config{Format: FormatDev, DisableTimestamp: true, Color: ColorNever}
+	enc, err := config.BuildZapEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc.AddString("app", "pkg")
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		LoggerName: "storage",
+		Message:    "hello world",
+		Caller:     zapcore.NewEntryCaller(0, "/src/pkg/logx/fs.go", 42, true),
+		Stack:      "main.main\n\t/src/main.go:5",
+	}, []zapcore.Field{
+		zap.Int("count", 3),
+		zap.Error(verboseError{}),
+		NamedErr("cause", errors.New("plain")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "WARN  logx/fs.go:42            storage: hello world  app=pkg count=3 error=boom cause=plain\n" +
+		"  error:\n" +
+		"    boom\n" +
+		"    main.run\n" +
+		"      main.go:10\n" +
+		"  stack:\n" +
+		"    main.main\n" +
+		"      /src/main.go:5\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s, got:\n%s", expected, buf.String())
+	}
+}
+
+func TestDevEncoderColor(t *testing.T) {
+	config := &Config{Format: FormatDev, DisableTimestamp: true, DisableCaller: true, OsStdout: true, Color: ColorAlways}
+	enc, err := config.BuildZapEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := _colorRed + "ERROR" + _colorReset + " failed\n"; buf.String() != expected {
+		t.Fatalf("Expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(ColorAuto, os.Stdout) {
+		t.Fatal("Unexpected colors with NO_COLOR")
+	}
+
+	if !colorEnabled(ColorAlways, os.Stdout) {
+		t.Fatal("Expected colors with always")
+	}
+
+	dir := t.TempDir()
+	lg, err := NewZapLogger(WithColor(ColorAlways), WithOutput(&OutputConfig{
+		Type: OutputFile, Format: FormatDev, File: NewFileConfig(dir, "dev.log", 10, 0, 0, false),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Error("failed")
+	lg.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "dev.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "\x1b[") {
+		t.Fatalf("Unexpected colors in file, got: %q", data)
+	}
+
+	err = (&Config{Format: FormatDev, OsStdout: true, Color: "rainbow"}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "color") {
+		t.Fatalf("Expected invalid color, got: %v", err)
+	}
+}
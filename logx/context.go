@@ -0,0 +1,194 @@
+package logx
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type contextFieldsKey struct{}
+
+type traceparentKey struct{}
+
+// ContextExtractor extracts fields from context, e.g. trace id.
+type ContextExtractor func(ctx context.Context) []zap.Field
+
+var (
+	_extractorsMu sync.RWMutex
+	_extractors   = []ContextExtractor{TraceparentExtractor}
+)
+
+// RegisterContextExtractor adds an extractor used by WithContext
+// and the *Ctx functions.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	_extractorsMu.Lock()
+	defer _extractorsMu.Unlock()
+
+	_extractors = append(_extractors, extractor)
+}
+
+// ContextWith returns a copy of ctx which carries fields, along
+// with the fields attached to ctx before.
+func ContextWith(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	old := ContextFields(ctx)
+	merged := make([]zap.Field, 0, len(old)+len(fields))
+	merged = append(merged, old...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, contextFieldsKey{}, merged)
+}
+
+// ContextFields returns the fields attached to ctx by ContextWith.
+func ContextFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(contextFieldsKey{}).([]zap.Field)
+	return fields
+}
+
+// extractFields returns the fields attached to ctx and the
+// ones found by extractors.
+func extractFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+
+	fields := ContextFields(ctx)
+
+	_extractorsMu.RLock()
+	defer _extractorsMu.RUnlock()
+
+	for _, extractor := range _extractors {
+		if extracted := extractor(ctx); len(extracted) > 0 {
+			fields = append(fields[:len(fields):len(fields)], extracted...)
+		}
+	}
+
+	return fields
+}
+
+// ContextWithTraceparent returns a copy of ctx which carries the W3C
+// traceparent header, e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// TraceparentExtractor adds trace_id, span_id and trace_flags from
+// the traceparent attached by ContextWithTraceparent.
+func TraceparentExtractor(ctx context.Context) []zap.Field {
+	traceparent, _ := ctx.Value(traceparentKey{}).(string)
+	if traceparent == "" {
+		return nil
+	}
+
+	traceID, spanID, flags, ok := parseTraceparent(traceparent)
+	if !ok {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", traceID),
+		zap.String("span_id", spanID),
+		zap.String("trace_flags", flags),
+	}
+}
+
+// parseTraceparent parses version-traceid-parentid-flags, see
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func parseTraceparent(s string) (string, string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", "", false
+	}
+
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return "", "", "", false
+	}
+
+	if !isHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return "", "", "", false
+	}
+
+	if !isHex(flags, 2) {
+		return "", "", "", false
+	}
+
+	return traceID, spanID, flags, true
+}
+
+// isHex checks if s is lower case hex string of n characters.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithContext returns the logger with the fields from ctx.
+func (z *zapLogger) WithContext(ctx context.Context) *zap.Logger {
+	return withContext(z.Logger(), ctx)
+}
+
+func withContext(lg *zap.Logger, ctx context.Context) *zap.Logger {
+	fields := extractFields(ctx)
+	if len(fields) == 0 {
+		return lg
+	}
+
+	return lg.With(fields...)
+}
+
+// WithContext returns the global logger with the fields from ctx.
+func WithContext(ctx context.Context) *zap.Logger {
+	return global().WithContext(ctx)
+}
+
+// DebugCtx logs a message at DebugLevel with the fields from ctx.
+func DebugCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Debug(msg, fields...)
+}
+
+// InfoCtx logs a message at InfoLevel with the fields from ctx.
+func InfoCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Info(msg, fields...)
+}
+
+// WarnCtx logs a message at WarnLevel with the fields from ctx.
+func WarnCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Warn(msg, fields...)
+}
+
+// ErrorCtx logs a message at ErrorLevel with the fields from ctx.
+func ErrorCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Error(msg, fields...)
+}
+
+// PanicCtx logs a message at PanicLevel with the fields from ctx.
+func PanicCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Panic(msg, fields...)
+}
+
+// FatalCtx logs a message at FatalLevel with the fields from ctx.
+func FatalCtx(ctx context.Context, msg string, fields ...zap.Field) {
+	withContext(global().globalLogger, ctx).Fatal(msg, fields...)
+}
@@ -0,0 +1,38 @@
+package logx
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestContextFields(t *testing.T) {
+	ctx := ContextWith(context.Background(), zap.String("tenant", "t1"))
+	ctx = ContextWith(ctx, zap.String("user", "u1"))
+	ctx = ContextWithTraceparent(ctx, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	fields := extractFields(ctx)
+	expected := map[string]string{
+		"tenant":      "t1",
+		"user":        "u1",
+		"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":     "00f067aa0ba902b7",
+		"trace_flags": "01",
+	}
+
+	if len(fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got: %d", len(expected), len(fields))
+	}
+
+	for _, f := range fields {
+		if expected[f.Key] != f.String {
+			t.Fatalf("Expected %s: %s, got: %s", f.Key, expected[f.Key], f.String)
+		}
+	}
+
+	ctx = ContextWithTraceparent(context.Background(), "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	if fields := extractFields(ctx); len(fields) != 0 {
+		t.Fatalf("Unexpected fields from invalid traceparent: %v", fields)
+	}
+}
@@ -0,0 +1,174 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chao77977/pkg/errorx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Err constructs a field of err with key "error", see NamedErr.
+func Err(err error) zap.Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr constructs a field of err as an object with the message, the
+// code of errorx.XError in the chain, whether it is unrecoverable, and
+// the verbose form like stack if DisableErrorVerbose is not set. The
+// entries of errorx.Errors are written as an array of such objects.
+func NamedErr(key string, err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+
+	return zap.Object(key, errorObject{err: err, verbose: true})
+}
+
+type errorObject struct {
+	err     error
+	verbose bool
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	msg := e.err.Error()
+	enc.AddString("message", msg)
+
+	// the code and status of the last entry are not for the whole
+	if errs := wrappedErrors(e.err); errs != nil {
+		return enc.AddArray("errors", errorArray{errs: errs, verbose: e.verbose})
+	}
+
+	var xerr errorx.XError
+	if errors.As(e.err, &xerr) {
+		enc.AddUint64("code", xerr.Code())
+	}
+
+	if errors.Is(e.err, errorx.Unrecoverable(nil)) {
+		enc.AddBool("unrecoverable", true)
+	}
+
+	if _, ok := e.err.(fmt.Formatter); ok && e.verbose {
+		if verbose := fmt.Sprintf("%+v", e.err); verbose != msg {
+			enc.AddString("verbose", verbose)
+		}
+	}
+
+	return nil
+}
+
+type errorArray struct {
+	errs    []error
+	verbose bool
+}
+
+func (a errorArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range a.errs {
+		if err == nil {
+			continue
+		}
+
+		if err := enc.AppendObject(errorObject{err: err, verbose: a.verbose}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func wrappedErrors(err error) []error {
+	var errs *errorx.Errors
+	if errors.As(err, &errs) && errs != nil {
+		return errs.WrappedErrors()
+	}
+
+	var errsValue errorx.Errors
+	if errors.As(err, &errsValue) {
+		return errsValue.WrappedErrors()
+	}
+
+	return nil
+}
+
+// noVerboseCore drops the verbose form of errors, it is used
+// if DisableErrorVerbose is set.
+type noVerboseCore struct {
+	core zapcore.Core
+}
+
+func (c *noVerboseCore) fields(fields []zapcore.Field) []zapcore.Field {
+	converted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch v := field.Interface.(type) {
+		case errorObject:
+			v.verbose = false
+			field.Interface = v
+		case error:
+			if field.Type == zapcore.ErrorType {
+				field = zap.String(field.Key, v.Error())
+			}
+		case zapcore.ArrayMarshaler:
+			if field.Type == zapcore.ArrayMarshalerType {
+				field.Interface = noVerboseArray{m: v}
+			}
+		}
+		converted[i] = field
+	}
+
+	return converted
+}
+
+func (c *noVerboseCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *noVerboseCore) With(fields []zapcore.Field) zapcore.Core {
+	return &noVerboseCore{core: c.core.With(c.fields(fields))}
+}
+
+func (c *noVerboseCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.core.Check(ent, nil) == nil {
+		return ce
+	}
+
+	return ce.AddCore(ent, c)
+}
+
+func (c *noVerboseCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.core.Write(ent, c.fields(fields))
+}
+
+func (c *noVerboseCore) Sync() error {
+	return c.core.Sync()
+}
+
+// noVerboseArray drops the verbose form of the errors in arrays,
+// e.g. zap.Errors, which are encoded as objects of key "error".
+type noVerboseArray struct {
+	m zapcore.ArrayMarshaler
+}
+
+func (a noVerboseArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.m.MarshalLogArray(&noVerboseArrayEncoder{ArrayEncoder: enc})
+}
+
+type noVerboseArrayEncoder struct {
+	zapcore.ArrayEncoder
+}
+
+func (e *noVerboseArrayEncoder) AppendObject(m zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		return m.MarshalLogObject(&noVerboseObjectEncoder{ObjectEncoder: enc})
+	}))
+}
+
+type noVerboseObjectEncoder struct {
+	zapcore.ObjectEncoder
+}
+
+func (e *noVerboseObjectEncoder) AddString(key, value string) {
+	if key != "errorVerbose" {
+		e.ObjectEncoder.AddString(key, value)
+	}
+}
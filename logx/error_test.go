@@ -0,0 +1,95 @@
+package logx
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chao77977/pkg/errorx"
+	"github.com/cockroachdb/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func readEntries(t *testing.T, path string) []map[string]interface{} {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestErr(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewZapLogger(WithFile(dir, "app.log", 10, 0, 0, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := errors.Wrap(errorx.New(404, "not found"), "get user")
+	errs := errorx.NewErrors(0)
+	errs.Add(errorx.Unrecoverable(errorx.New(500, "broken")))
+	errs.Add(errors.New("plain"))
+
+	lg.Logger().Error("wrapped", Err(notFound))
+	lg.Logger().Error("multiple", Err(errs))
+	lg.Logger().Error("nil", Err(nil))
+	lg.Close()
+
+	entries := readEntries(t, filepath.Join(dir, "app.log"))
+	wrapped := entries[0]["error"].(map[string]interface{})
+	if wrapped["code"] != float64(404) || wrapped["message"] != notFound.Error() || wrapped["verbose"] == nil {
+		t.Fatalf("Expected code 404 with verbose, got: %v", wrapped)
+	}
+
+	multiple := entries[1]["error"].(map[string]interface{})["errors"].([]interface{})
+	first := multiple[0].(map[string]interface{})
+	second := multiple[1].(map[string]interface{})
+	if len(multiple) != 2 || first["code"] != float64(500) || first["unrecoverable"] != true ||
+		second["message"] != "plain" || second["code"] != nil {
+		t.Fatalf("Expected 2 errors, got: %v", multiple)
+	}
+
+	if _, ok := entries[2]["error"]; ok {
+		t.Fatalf("Expected no error field, got: %v", entries[2])
+	}
+
+	lg, err = NewZapLogger(WithFile(dir, "quiet.log", 10, 0, 0, false), WithDisableErrorVerbose())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Error("wrapped", Err(notFound), zap.NamedError("cause", notFound), zap.Errors("causes", []error{notFound}))
+	lg.Close()
+
+	entries = readEntries(t, filepath.Join(dir, "quiet.log"))
+	if entries[0]["error"].(map[string]interface{})["verbose"] != nil || entries[0]["causeVerbose"] != nil || entries[0]["cause"] != notFound.Error() {
+		t.Fatalf("Expected no verbose, got: %v", entries[0])
+	}
+
+	causes := entries[0]["causes"].([]interface{})
+	if cause := causes[0].(map[string]interface{}); cause["error"] != notFound.Error() || cause["errorVerbose"] != nil {
+		t.Fatalf("Expected no verbose in array, got: %v", causes)
+	}
+
+	core := &noVerboseCore{core: zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(failedWriter{}),
+		zapcore.DebugLevel,
+	)}
+	if err := core.Write(zapcore.Entry{Message: "lost"}, nil); err == nil {
+		t.Fatal("Expected the error of writing")
+	}
+}
@@ -0,0 +1,264 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const _defaultJournalSocket = "/run/systemd/journal/socket"
+
+// JournaldConfig represents a journald output in toml/json, entries are
+// sent to Socket by the native protocol, the fields are upper cased as
+// the fields of journal, e.g. "user_id" as "USER_ID". Identifier is the
+// SYSLOG_IDENTIFIER, which is the name of program by default.
+type JournaldConfig struct {
+	Socket     string `toml:"socket" json:"socket"`
+	Identifier string `toml:"identifier" json:"identifier"`
+}
+
+// the fields written by journaldCore, the keys colliding with them
+// are prefixed like the ones starting with a digit.
+var _journalReserved = map[string]bool{
+	"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true, "LOGGER": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true, "STACK": true,
+}
+
+// journalFieldName converts key to the name of journal field, which has
+// only upper case letters, digits and underscores, and cannot start with
+// an underscore or a digit.
+func journalFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+
+	s := strings.TrimLeft(string(name), "_")
+	if s != "" && (s[0] >= '0' && s[0] <= '9' || _journalReserved[s]) {
+		s = "F_" + s
+	}
+
+	if len(s) > 64 {
+		s = s[:64]
+	}
+
+	return s
+}
+
+// appendJournalField writes the value of multiple lines with its length,
+// see https://systemd.io/JOURNAL_NATIVE_PROTOCOL/.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func journalValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+type journalConn struct {
+	addr *net.UnixAddr
+
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	closed bool
+}
+
+func (c *journalConn) dial() error {
+	conn, err := net.DialUnix("unixgram", nil, c.addr)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	return nil
+}
+
+// write sends the large payload through a file descriptor, and
+// reconnects once if journald is restarted.
+func (c *journalConn) write(payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errOutputClosed
+	}
+
+	for retry := 0; ; retry++ {
+		if c.conn == nil {
+			if err := c.dial(); err != nil {
+				return err
+			}
+		}
+
+		_, err := c.conn.Write(payload)
+		if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+			return sendJournalFile(c.conn, payload)
+		}
+
+		if err == nil || retry > 0 {
+			return err
+		}
+
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// Write sends payload as an entry of journald.
+func (c *journalConn) Write(payload []byte) (int, error) {
+	if err := c.write(payload); err != nil {
+		return 0, err
+	}
+
+	return len(payload), nil
+}
+
+func (c *journalConn) Sync() error {
+	return nil
+}
+
+func (c *journalConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func newJournalConn(config *JournaldConfig) (*journalConn, error) {
+	socket := config.Socket
+	if socket == "" {
+		socket = _defaultJournalSocket
+	}
+
+	conn := &journalConn{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}}
+	if err := conn.dial(); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// journaldCore writes entries to journald through ws, which is the
+// journalConn or the async writer of it. The level is mapped to
+// PRIORITY, and caller to CODE_FILE, CODE_LINE and CODE_FUNC.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	ws         zapcore.WriteSyncer
+	identifier string
+	fields     []zapcore.Field
+}
+
+func newJournaldCore(config *JournaldConfig, ws zapcore.WriteSyncer, enab zapcore.LevelEnabler) *journaldCore {
+	identifier := config.Identifier
+	if identifier == "" {
+		identifier = programName()
+	}
+
+	return &journaldCore{LevelEnabler: enab, ws: ws, identifier: identifier}
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf := &bytes.Buffer{}
+	appendJournalField(buf, "MESSAGE", ent.Message)
+	appendJournalField(buf, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	appendJournalField(buf, "SYSLOG_IDENTIFIER", c.identifier)
+	if ent.LoggerName != "" {
+		appendJournalField(buf, "LOGGER", ent.LoggerName)
+	}
+
+	if ent.Caller.Defined {
+		appendJournalField(buf, "CODE_FILE", ent.Caller.File)
+		appendJournalField(buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		appendJournalField(buf, "CODE_FUNC", ent.Caller.Function)
+	}
+
+	if ent.Stack != "" {
+		appendJournalField(buf, "STACK", ent.Stack)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	keys := make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if name := journalFieldName(key); name != "" {
+			appendJournalField(buf, name, journalValue(enc.Fields[key]))
+		}
+	}
+
+	_, err := c.ws.Write(buf.Bytes())
+	return err
+}
+
+func (c *journaldCore) Sync() error {
+	return c.ws.Sync()
+}
@@ -0,0 +1,28 @@
+package logx
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournalFile sends payload in a file on tmpfs, since it is too
+// large for a datagram.
+func sendJournalFile(conn *net.UnixConn, payload []byte) error {
+	f, err := os.CreateTemp("/dev/shm", "journal.*")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+
+	if _, err := f.Write(payload); err != nil {
+		return err
+	}
+
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), nil)
+	return err
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package logx
+
+import (
+	"errors"
+	"net"
+)
+
+func sendJournalFile(conn *net.UnixConn, payload []byte) error {
+	return errors.New("message is too large for journald")
+}
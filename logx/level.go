@@ -0,0 +1,131 @@
+package logx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var _levelNames = map[Level]string{
+	LvlFatal:  "fatal",
+	LvlPanic:  "panic",
+	LvlDPanic: "dpanic",
+	LvlError:  "error",
+	LvlWarn:   "warn",
+	LvlInfo:   "info",
+	LvlDebug:  "debug",
+}
+
+func (l Level) String() string {
+	if name, ok := _levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel parses level name, e.g. "debug", "INFO".
+func ParseLevel(s string) (Level, error) {
+	for lvl, name := range _levelNames {
+		if strings.EqualFold(s, name) {
+			return lvl, nil
+		}
+	}
+
+	if strings.EqualFold(s, "warning") {
+		return LvlWarn, nil
+	}
+
+	return 0, fmt.Errorf("invalid log level '%s'", s)
+}
+
+// UnmarshalText accepts level name or number, so that level can be
+// written as "info" in TOML or environment variables.
+func (l *Level) UnmarshalText(text []byte) error {
+	if n, err := strconv.Atoi(string(text)); err == nil {
+		*l = Level(n)
+		return nil
+	}
+
+	lvl, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+
+	*l = lvl
+	return nil
+}
+
+// UnmarshalJSON accepts level as number or name, e.g. 5 or "info".
+func (l *Level) UnmarshalJSON(data []byte) error {
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		return l.UnmarshalText([]byte(s))
+	}
+
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid log level %s", data)
+	}
+
+	*l = Level(n)
+	return nil
+}
+
+type levelPayload struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type levelHandler struct {
+	getLevel func() Level
+	setLevel func(Level) error
+}
+
+// LevelHandler returns a http.Handler which reads the level of logger
+// on GET, and changes it on PUT with JSON body like {"level":"debug"}.
+func (z *zapLogger) LevelHandler() http.Handler {
+	return &levelHandler{getLevel: z.GetLevel, setLevel: z.SetLevel}
+}
+
+// LevelHandler returns a http.Handler to read and change the level
+// of global logger.
+func LevelHandler() http.Handler {
+	return &levelHandler{getLevel: GetLevel, setLevel: SetLevel}
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		req := levelPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+			return
+		}
+
+		lvl, err := ParseLevel(req.Level)
+		if err == nil {
+			err = h.setLevel(lvl)
+		}
+
+		if err != nil {
+			writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelPayload(w, http.StatusMethodNotAllowed,
+			levelPayload{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+		return
+	}
+
+	writeLevelPayload(w, http.StatusOK, levelPayload{Level: h.getLevel().String()})
+}
+
+func writeLevelPayload(w http.ResponseWriter, code int, payload levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,66 @@
+package logx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSetLevel(t *testing.T) {
+	lg, err := InitZapLogger(&Config{Lvl: LvlDebug, Format: FormatJson, OsStdout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := lg.Logger()
+	if !logger.Core().Enabled(zap.DebugLevel) {
+		t.Fatal("Expected debug level enabled")
+	}
+
+	if err := lg.SetLevel(LvlWarn); err != nil {
+		t.Fatal(err)
+	}
+
+	if logger.Core().Enabled(zap.InfoLevel) || !logger.Core().Enabled(zap.WarnLevel) {
+		t.Fatalf("Expected level: warn, got: %s", lg.GetLevel())
+	}
+
+	if err := lg.SetLevel(Level(100)); err == nil {
+		t.Fatal("Unexpected level 100")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	lg, err := InitZapLogger(&Config{Lvl: LvlInfo, Format: FormatJson, OsStdout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(lg.LevelHandler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(`{"level":"error"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || lg.GetLevel() != LvlError {
+		t.Fatalf("Expected level: error, got: %s", lg.GetLevel())
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(`{"level":"verbose"}`))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status: 400, got: %d", resp.StatusCode)
+	}
+}
@@ -1,6 +1,10 @@
 package logx
 
 import (
+	"context"
+	"sync/atomic"
+	"time"
+
 	"go.uber.org/zap"
 )
 
@@ -20,42 +24,132 @@ const (
 	FormatJson    = "json"
 	FormatConsole = "console"
 	FormatText    = "text"
+	FormatDev     = "dev"
 )
 
-var _globalLogger *zapLogger
+var _globalLogger atomic.Value // *zapLogger
+
+func global() *zapLogger {
+	return _globalLogger.Load().(*zapLogger)
+}
+
+type globalsConfig struct {
+	stdLog     bool
+	zapGlobals bool
+}
+
+type globalsConfigurer func(*globalsConfig)
+
+// RedirectStdLog makes ReplaceGlobals also redirect the output of
+// the standard library log package to the logger at info level.
+func RedirectStdLog() globalsConfigurer {
+	return func(c *globalsConfig) {
+		c.stdLog = true
+	}
+}
+
+// ReplaceZapGlobals makes ReplaceGlobals also replace zap.L() and zap.S().
+func ReplaceZapGlobals() globalsConfigurer {
+	return func(c *globalsConfig) {
+		c.zapGlobals = true
+	}
+}
+
+// ReplaceGlobals replaces the global logger with lg, and returns a
+// function to restore the previous one. It is safe to call while the
+// global logger is in use.
+func ReplaceGlobals(lg *zapLogger, configs ...globalsConfigurer) func() {
+	c := &globalsConfig{}
+	for _, config := range configs {
+		config(c)
+	}
+
+	restores := []func(){}
+	if c.zapGlobals {
+		restores = append(restores, zap.ReplaceGlobals(lg.Logger()))
+	}
+
+	if c.stdLog {
+		restores = append(restores, zap.RedirectStdLog(lg.Logger()))
+	}
+
+	prev := _globalLogger.Swap(lg).(*zapLogger)
+	return func() {
+		_globalLogger.Store(prev)
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+}
+
+// Sync flushes the global logger, it is called before the program exits.
+func Sync() error {
+	return global().Sync()
+}
+
+// GetLevel returns the level of global logger.
+func GetLevel() Level {
+	return global().GetLevel()
+}
+
+// SetLevel changes the level of global logger at runtime.
+func SetLevel(lvl Level) error {
+	return global().SetLevel(lvl)
+}
+
+// WatchConfig reloads the global logger when the config file at
+// path is modified, see zapLogger.WatchConfig.
+func WatchConfig(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	global().WatchConfig(ctx, path, interval, onError)
+}
+
+// Named returns the global logger named by name, see zapLogger.Named.
+func Named(name string) *zap.Logger {
+	return global().Named(name)
+}
+
+// SNamed returns the sugared global logger named by name.
+func SNamed(name string) *zap.SugaredLogger {
+	return global().SNamed(name)
+}
+
+// SetModuleLevel changes the level of named global loggers at runtime.
+func SetModuleLevel(name string, lvl Level) error {
+	return global().SetModuleLevel(name, lvl)
+}
 
 // Debug logs a message at DebugLevel.
 func Debug(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Debug(msg, fields...)
+	global().globalLogger.Debug(msg, fields...)
 }
 
 // Info logs a message at InfoLevel.
 func Info(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Info(msg, fields...)
+	global().globalLogger.Info(msg, fields...)
 }
 
 // Warn logs a message at WarnLevel.
 func Warn(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Warn(msg, fields...)
+	global().globalLogger.Warn(msg, fields...)
 }
 
 // Error logs a message at ErrorLevel.
 func Error(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Error(msg, fields...)
+	global().globalLogger.Error(msg, fields...)
 }
 
 // Panic logs a message at PanicLevel.
 func Panic(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Panic(msg, fields...)
+	global().globalLogger.Panic(msg, fields...)
 }
 
 // Fatal logs a message at FatalLevel.
 func Fatal(msg string, fields ...zap.Field) {
-	_globalLogger.Logger().Fatal(msg, fields...)
+	global().globalLogger.Fatal(msg, fields...)
 }
 
 func init() {
 	config := NewDefaultConfig()
 	lg, _ := InitZapLogger(config)
-	_globalLogger = lg
+	_globalLogger.Store(lg)
 }
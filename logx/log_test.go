@@ -0,0 +1,111 @@
+package logx
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestReplaceGlobals(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := InitZapLogger(newConfig(WithFile(dir, "app.log", 10, 0, 0, false)), zap.AddCaller())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := global()
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				GetLevel()
+			}
+		}
+	}()
+
+	restore := ReplaceGlobals(lg, RedirectStdLog(), ReplaceZapGlobals())
+	Info("from logx")
+	log.Print("from log")
+	zap.L().Info("from zap")
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	restore()
+
+	close(stop)
+	wg.Wait()
+
+	if global() != prev {
+		t.Fatal("Expected global logger restored")
+	}
+
+	Info("after restore")
+	lg.Close()
+
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	for _, msg := range []string{"from logx", "from log", "from zap"} {
+		if !strings.Contains(string(content), msg) {
+			t.Fatalf("Expected %s, got: %s", msg, content)
+		}
+	}
+
+	if strings.Contains(string(content), "after restore") {
+		t.Fatalf("Unexpected entry after restore, got: %s", content)
+	}
+
+	if !strings.Contains(string(content), "logx/log_test.go") {
+		t.Fatalf("Expected caller of test, got: %s", content)
+	}
+}
+
+func TestInitZapLoggerOptions(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{
+		Lvl:     LvlDebug,
+		Format:  FormatJson,
+		File:    NewFileConfig(dir, "app.log", 10, 0, 0, false),
+		Process: &ProcessConfig{Service: "billing"},
+	}
+	lg, err := InitZapLogger(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Info("without options")
+	lg.Close()
+
+	// the options of config are applied after the given ones
+	config.File.Name = "opts.log"
+	lg, err = InitZapLogger(config, zap.WithCaller(false), zap.Fields(zap.String("app", "test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Info("with options")
+	lg.Close()
+
+	// the options of config are only applied with options as before,
+	// but the process fields are always attached
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if strings.Contains(string(content), `"caller"`) || !strings.Contains(string(content), `"service":"billing"`) {
+		t.Fatalf("Expected process fields without caller, got: %s", content)
+	}
+
+	content, _ = os.ReadFile(filepath.Join(dir, "opts.log"))
+	for _, s := range []string{`"caller":"logx/log_test.go`, `"app":"test"`, `"service":"billing"`} {
+		if !strings.Contains(string(content), s) {
+			t.Fatalf("Expected %s in opts.log, got: %s", s, content)
+		}
+	}
+}
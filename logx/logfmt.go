@@ -0,0 +1,403 @@
+package logx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _logfmtPool = buffer.NewPool()
+
+// logfmtEncoder encodes entries as key=value pairs, nested objects
+// are flattened with dotted keys, e.g. req.method=GET.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string
+}
+
+// NewLogfmtEncoder creates a zapcore.Encoder in logfmt style.
+func NewLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{
+		EncoderConfig: &config,
+		buf:           _logfmtPool.Get(),
+	}
+}
+
+func (e *logfmtEncoder) addKey(key string) {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+
+	writeLogfmtKey(e.buf, e.prefix+key)
+	e.buf.AppendByte('=')
+}
+
+func (e *logfmtEncoder) addValue(key, value string) {
+	e.addKey(key)
+	writeLogfmtValue(e.buf, value)
+}
+
+func (e *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	a := &logfmtArray{enc: e}
+	err := arr.MarshalLogArray(a)
+	e.addValue(key, a.String())
+	return err
+}
+
+func (e *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	prefix := e.prefix
+	e.prefix = prefix + key + "."
+	err := obj.MarshalLogObject(e)
+	e.prefix = prefix
+	return err
+}
+
+func (e *logfmtEncoder) AddBinary(key string, value []byte) {
+	e.addValue(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, value []byte) {
+	e.addValue(key, string(value))
+}
+
+func (e *logfmtEncoder) AddBool(key string, value bool) {
+	e.addValue(key, strconv.FormatBool(value))
+}
+
+func (e *logfmtEncoder) AddComplex128(key string, value complex128) {
+	e.addValue(key, formatComplex(value, 64))
+}
+
+func (e *logfmtEncoder) AddComplex64(key string, value complex64) {
+	e.addValue(key, formatComplex(complex128(value), 32))
+}
+
+func (e *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	e.addValue(key, e.formatDuration(value))
+}
+
+func (e *logfmtEncoder) AddFloat64(key string, value float64) {
+	e.addValue(key, formatFloat(value, 64))
+}
+
+func (e *logfmtEncoder) AddFloat32(key string, value float32) {
+	e.addValue(key, formatFloat(float64(value), 32))
+}
+
+func (e *logfmtEncoder) AddInt(key string, value int)     { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+func (e *logfmtEncoder) AddInt8(key string, value int8)   { e.AddInt64(key, int64(value)) }
+
+func (e *logfmtEncoder) AddInt64(key string, value int64) {
+	e.addValue(key, strconv.FormatInt(value, 10))
+}
+
+func (e *logfmtEncoder) AddString(key, value string) {
+	e.addValue(key, value)
+}
+
+func (e *logfmtEncoder) AddTime(key string, value time.Time) {
+	e.addValue(key, e.formatTime(value))
+}
+
+func (e *logfmtEncoder) AddUint(key string, value uint)       { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint32(key string, value uint32)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint16(key string, value uint16)   { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUint8(key string, value uint8)     { e.AddUint64(key, uint64(value)) }
+func (e *logfmtEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+func (e *logfmtEncoder) AddUint64(key string, value uint64) {
+	e.addValue(key, strconv.FormatUint(value, 10))
+}
+
+func (e *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	s, err := formatReflected(value)
+	if err != nil {
+		return err
+	}
+
+	e.addValue(key, s)
+	return nil
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return e.clone()
+}
+
+func (e *logfmtEncoder) clone() *logfmtEncoder {
+	buf := _logfmtPool.Get()
+	buf.Write(e.buf.Bytes())
+	return &logfmtEncoder{
+		EncoderConfig: e.EncoderConfig,
+		buf:           buf,
+		prefix:        e.prefix,
+	}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{
+		EncoderConfig: e.EncoderConfig,
+		buf:           _logfmtPool.Get(),
+	}
+
+	if e.TimeKey != "" {
+		final.addValue(e.TimeKey, e.formatTime(ent.Time))
+	}
+
+	if e.LevelKey != "" && e.EncodeLevel != nil {
+		a := &logfmtArray{enc: e}
+		e.EncodeLevel(ent.Level, a)
+		final.addValue(e.LevelKey, a.join())
+	}
+
+	if ent.LoggerName != "" && e.NameKey != "" {
+		name := ent.LoggerName
+		if e.EncodeName != nil {
+			a := &logfmtArray{enc: e}
+			e.EncodeName(name, a)
+			name = a.join()
+		}
+
+		final.addValue(e.NameKey, name)
+	}
+
+	if ent.Caller.Defined {
+		if e.CallerKey != "" {
+			caller := ent.Caller.TrimmedPath()
+			if e.EncodeCaller != nil {
+				a := &logfmtArray{enc: e}
+				e.EncodeCaller(ent.Caller, a)
+				caller = a.join()
+			}
+
+			final.addValue(e.CallerKey, caller)
+		}
+
+		if e.FunctionKey != "" {
+			final.addValue(e.FunctionKey, ent.Caller.Function)
+		}
+	}
+
+	if e.MessageKey != "" {
+		final.addValue(e.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(e.buf.Bytes())
+	}
+
+	// fields of the entry share the namespaces opened by context
+	final.prefix = e.prefix
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.prefix = ""
+
+	if ent.Stack != "" && e.StacktraceKey != "" {
+		final.addValue(e.StacktraceKey, ent.Stack)
+	}
+
+	lineEnding := e.LineEnding
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	final.buf.AppendString(lineEnding)
+
+	return final.buf, nil
+}
+
+func (e *logfmtEncoder) formatTime(t time.Time) string {
+	if e.EncodeTime == nil {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	a := &logfmtArray{enc: e}
+	e.EncodeTime(t, a)
+	return a.join()
+}
+
+func (e *logfmtEncoder) formatDuration(d time.Duration) string {
+	if e.EncodeDuration == nil {
+		return d.String()
+	}
+
+	a := &logfmtArray{enc: e}
+	e.EncodeDuration(d, a)
+	return a.join()
+}
+
+// logfmtArray collects the formatted elements of an array, it is
+// also used to capture the output of time, level and caller encoders.
+type logfmtArray struct {
+	enc   *logfmtEncoder
+	elems []string
+}
+
+func (a *logfmtArray) append(s string) {
+	a.elems = append(a.elems, s)
+}
+
+func (a *logfmtArray) join() string {
+	return strings.Join(a.elems, " ")
+}
+
+func (a *logfmtArray) String() string {
+	return "[" + strings.Join(a.elems, ",") + "]"
+}
+
+func (a *logfmtArray) AppendArray(arr zapcore.ArrayMarshaler) error {
+	sub := &logfmtArray{enc: a.enc}
+	err := arr.MarshalLogArray(sub)
+	a.append(sub.String())
+	return err
+}
+
+func (a *logfmtArray) AppendObject(obj zapcore.ObjectMarshaler) error {
+	sub := &logfmtEncoder{
+		EncoderConfig: a.enc.EncoderConfig,
+		buf:           _logfmtPool.Get(),
+	}
+	defer sub.buf.Free()
+
+	err := obj.MarshalLogObject(sub)
+	a.append("{" + sub.buf.String() + "}")
+	return err
+}
+
+func (a *logfmtArray) AppendReflected(value interface{}) error {
+	s, err := formatReflected(value)
+	if err != nil {
+		return err
+	}
+
+	a.append(s)
+	return nil
+}
+
+func (a *logfmtArray) AppendBool(v bool)              { a.append(strconv.FormatBool(v)) }
+func (a *logfmtArray) AppendByteString(v []byte)      { a.append(string(v)) }
+func (a *logfmtArray) AppendComplex128(v complex128)  { a.append(formatComplex(v, 64)) }
+func (a *logfmtArray) AppendComplex64(v complex64)    { a.append(formatComplex(complex128(v), 32)) }
+func (a *logfmtArray) AppendFloat64(v float64)        { a.append(formatFloat(v, 64)) }
+func (a *logfmtArray) AppendFloat32(v float32)        { a.append(formatFloat(float64(v), 32)) }
+func (a *logfmtArray) AppendInt(v int)                { a.AppendInt64(int64(v)) }
+func (a *logfmtArray) AppendInt64(v int64)            { a.append(strconv.FormatInt(v, 10)) }
+func (a *logfmtArray) AppendInt32(v int32)            { a.AppendInt64(int64(v)) }
+func (a *logfmtArray) AppendInt16(v int16)            { a.AppendInt64(int64(v)) }
+func (a *logfmtArray) AppendInt8(v int8)              { a.AppendInt64(int64(v)) }
+func (a *logfmtArray) AppendString(v string)          { a.append(v) }
+func (a *logfmtArray) AppendUint(v uint)              { a.AppendUint64(uint64(v)) }
+func (a *logfmtArray) AppendUint64(v uint64)          { a.append(strconv.FormatUint(v, 10)) }
+func (a *logfmtArray) AppendUint32(v uint32)          { a.AppendUint64(uint64(v)) }
+func (a *logfmtArray) AppendUint16(v uint16)          { a.AppendUint64(uint64(v)) }
+func (a *logfmtArray) AppendUint8(v uint8)            { a.AppendUint64(uint64(v)) }
+func (a *logfmtArray) AppendUintptr(v uintptr)        { a.AppendUint64(uint64(v)) }
+func (a *logfmtArray) AppendDuration(v time.Duration) { a.append(a.enc.formatDuration(v)) }
+func (a *logfmtArray) AppendTime(v time.Time)         { a.append(a.enc.formatTime(v)) }
+
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+func formatComplex(c complex128, bitSize int) string {
+	return strconv.FormatComplex(c, 'g', -1, bitSize*2)
+}
+
+func formatReflected(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// writeLogfmtKey writes the key with the characters which are not
+// allowed in logfmt keys replaced by '_'.
+func writeLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			buf.AppendByte('_')
+			continue
+		}
+
+		buf.AppendString(string(r))
+	}
+}
+
+func needsLogfmtQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeLogfmtValue writes the value, it is quoted and escaped if
+// it is empty or has space, '=', '"' or control characters.
+func writeLogfmtValue(buf *buffer.Buffer, s string) {
+	if !needsLogfmtQuote(s) {
+		buf.AppendString(s)
+		return
+	}
+
+	buf.AppendByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.AppendByte('\\')
+			buf.AppendByte(byte(r))
+		case '\n':
+			buf.AppendString(`\n`)
+		case '\r':
+			buf.AppendString(`\r`)
+		case '\t':
+			buf.AppendString(`\t`)
+		default:
+			if r < ' ' || r == utf8.RuneError {
+				buf.AppendString(fmt.Sprintf(`\u%04x`, r))
+				continue
+			}
+
+			buf.AppendString(string(r))
+		}
+	}
+	buf.AppendByte('"')
+}
@@ -0,0 +1,46 @@
+package logx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	config := &Config{Format: FormatText, DisableTimestamp: true}
+	enc, err := config.BuildZapEncoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc.AddString("app", "pkg")
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Message: "hello world",
+	}, []zapcore.Field{
+		zap.Int("count", 3),
+		zap.String("quote", `say "hi"`),
+		zap.Duration("elapsed", 1500*time.Millisecond),
+		zap.Error(errors.New("boom")),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Dict("req", zap.String("method", "GET"), zap.String("path", "/")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `level=INFO message="hello world" app=pkg count=3 quote="say \"hi\"" ` +
+		`elapsed=1.5s error=boom tags=[a,b] req.method=GET req.path=/` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected: %s, got: %s", expected, buf.String())
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	if _, err := InitZapLogger(&Config{Format: "xml", OsStdout: true}); err == nil {
+		t.Fatal("Unexpected logger with format xml")
+	}
+}
@@ -0,0 +1,179 @@
+// Package logxtest captures the entries of logx loggers in memory,
+// so that tests can assert on what is logged.
+package logxtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chao77977/pkg/logx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry is a captured log entry, Fields includes the ones added
+// by With.
+type Entry struct {
+	Level      logx.Level
+	Message    string
+	LoggerName string
+	Time       time.Time
+	Fields     map[string]interface{}
+}
+
+// Observer captures the entries logged by its loggers.
+type Observer struct {
+	logs    *observer.ObservedLogs
+	logger  *zap.Logger
+	sLogger *zap.SugaredLogger
+}
+
+// New returns an Observer capturing the entries from level lvl.
+func New(lvl logx.Level) *Observer {
+	o, _ := newObserver(lvl)
+	return o
+}
+
+// Replace replaces the global logger with an Observer capturing the
+// entries from level lvl, the previous one is restored when the
+// test finishes.
+func Replace(t testing.TB, lvl logx.Level) *Observer {
+	t.Helper()
+
+	o, replace := newObserver(lvl)
+	t.Cleanup(replace())
+	return o
+}
+
+// newObserver also returns a function to replace the global
+// logger with the observer.
+func newObserver(lvl logx.Level) (*Observer, func() func()) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	config := logx.NewDefaultConfig()
+	config.Lvl = lvl
+	lg, err := logx.InitZapLogger(config, zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return core
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	o := &Observer{logs: logs, logger: lg.Logger(), sLogger: lg.SLogger()}
+	return o, func() func() {
+		return logx.ReplaceGlobals(lg)
+	}
+}
+
+// Logger returns the logger writing to o.
+func (o *Observer) Logger() *zap.Logger {
+	return o.logger
+}
+
+// SLogger returns the sugared logger writing to o.
+func (o *Observer) SLogger() *zap.SugaredLogger {
+	return o.sLogger
+}
+
+// Entries returns the entries captured so far.
+func (o *Observer) Entries() []Entry {
+	logged := o.logs.All()
+	entries := make([]Entry, 0, len(logged))
+	for _, e := range logged {
+		lvl, _ := logx.ParseLevel(e.Level.String())
+		entries = append(entries, Entry{
+			Level:      lvl,
+			Message:    e.Message,
+			LoggerName: e.LoggerName,
+			Time:       e.Time,
+			Fields:     e.ContextMap(),
+		})
+	}
+
+	return entries
+}
+
+// Reset drops the entries captured so far.
+func (o *Observer) Reset() {
+	o.logs.TakeAll()
+}
+
+// Messages returns the entries of message msg.
+func (o *Observer) Messages(msg string) []Entry {
+	entries := []Entry{}
+	for _, e := range o.Entries() {
+		if e.Message == msg {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// Count returns the number of entries of level lvl.
+func (o *Observer) Count(lvl logx.Level) int {
+	n := 0
+	for _, e := range o.Entries() {
+		if e.Level == lvl {
+			n++
+		}
+	}
+
+	return n
+}
+
+// AssertMessage fails the test if no entry of message msg is captured.
+func (o *Observer) AssertMessage(t testing.TB, msg string) {
+	t.Helper()
+
+	if len(o.Messages(msg)) == 0 {
+		t.Fatalf("Expected message %q, got: %v", msg, o.messages())
+	}
+}
+
+// AssertField fails the test if no entry of message msg has field key
+// of value expected, the values are compared in the form of %v, so
+// that 42 equals int64(42).
+func (o *Observer) AssertField(t testing.TB, msg, key string, expected interface{}) {
+	t.Helper()
+
+	entries := o.Messages(msg)
+	if len(entries) == 0 {
+		t.Fatalf("Expected message %q, got: %v", msg, o.messages())
+	}
+
+	values := []interface{}{}
+	for _, e := range entries {
+		value, ok := e.Fields[key]
+		if ok && fmt.Sprint(value) == fmt.Sprint(expected) {
+			return
+		}
+
+		if ok {
+			values = append(values, value)
+		}
+	}
+
+	t.Fatalf("Expected %s=%v of message %q, got: %v", key, expected, msg, values)
+}
+
+// AssertCount fails the test if the number of entries of level
+// lvl is not n.
+func (o *Observer) AssertCount(t testing.TB, lvl logx.Level, n int) {
+	t.Helper()
+
+	if count := o.Count(lvl); count != n {
+		t.Fatalf("Expected %d %s entries, got: %d", n, lvl, count)
+	}
+}
+
+func (o *Observer) messages() []string {
+	messages := []string{}
+	for _, e := range o.Entries() {
+		messages = append(messages, e.Message)
+	}
+
+	return messages
+}
@@ -0,0 +1,77 @@
+package logxtest
+
+import (
+	"testing"
+
+	"github.com/chao77977/pkg/logx"
+	"go.uber.org/zap"
+)
+
+func TestReplace(t *testing.T) {
+	prev := Replace(t, logx.LvlDebug)
+	var replaced *Observer
+	t.Run("replaced", func(t *testing.T) {
+		o := Replace(t, logx.LvlInfo)
+		replaced = o
+		logx.Debug("ignored")
+		logx.Info("hello", zap.String("user", "alice"), zap.Int("retries", 3))
+		logx.Named("storage").Warn("slow disk")
+		logx.Error("failed")
+
+		o.AssertMessage(t, "hello")
+		o.AssertField(t, "hello", "user", "alice")
+		o.AssertField(t, "hello", "retries", 3)
+		o.AssertCount(t, logx.LvlDebug, 0)
+		o.AssertCount(t, logx.LvlWarn, 1)
+
+		entries := o.Messages("slow disk")
+		if len(entries) != 1 || entries[0].LoggerName != "storage" {
+			t.Fatalf("Expected entry of storage logger, got: %v", entries)
+		}
+
+		o.Reset()
+		if len(o.Entries()) != 0 {
+			t.Fatalf("Expected no entries, got: %v", o.Entries())
+		}
+	})
+
+	// the previous global logger is restored
+	logx.Info("global")
+	prev.AssertMessage(t, "global")
+	if n := len(replaced.Messages("global")); n != 0 {
+		t.Fatalf("Expected no global entry after restore, got: %d", n)
+	}
+
+	o := New(logx.LvlDebug)
+	o.Logger().Info("local")
+	if len(o.Entries()) != 1 || o.Entries()[0].Message != "local" {
+		t.Fatalf("Expected local entry only, got: %v", o.Entries())
+	}
+}
+
+func TestReplaceConcurrent(t *testing.T) {
+	Replace(t, logx.LvlDebug)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				logx.Debug("concurrent")
+			}
+		}
+	}()
+
+	// the global logger is swapped while it is in use
+	for i := 0; i < 100; i++ {
+		t.Run("replaced", func(t *testing.T) {
+			Replace(t, logx.LvlInfo)
+		})
+	}
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,208 @@
+package logx
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Metrics receives the counts of logging, it must be safe for
+// concurrent use. Sink is the name of output, or "file" and "stdout"
+// for File and OsStdout.
+type Metrics interface {
+	// AddEntry is called for each entry written to the outputs, after
+	// sampling and deduplication.
+	AddEntry(lvl Level, logger string)
+	// AddBytes is called with the bytes written to sink.
+	AddBytes(sink string, n int)
+	// AddDropped is called with the entries dropped by sink, for the
+	// full queue of Async or the failures of network output.
+	AddDropped(sink string, n uint64)
+}
+
+// meter reports the bytes and drops of a sink to metrics if any.
+type meter struct {
+	metrics Metrics
+	sink    string
+}
+
+func (m meter) addBytes(n int) {
+	if m.metrics != nil && n > 0 {
+		m.metrics.AddBytes(m.sink, n)
+	}
+}
+
+func (m meter) addDropped(n uint64) {
+	if m.metrics != nil && n > 0 {
+		m.metrics.AddDropped(m.sink, n)
+	}
+}
+
+func (c *Config) meter(sink string) meter {
+	return meter{metrics: c.Metrics, sink: sink}
+}
+
+// meteredWriteSyncer counts the bytes written to WriteSyncer.
+type meteredWriteSyncer struct {
+	zapcore.WriteSyncer
+	meter meter
+}
+
+func (w *meteredWriteSyncer) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.meter.addBytes(n)
+	return n, err
+}
+
+// metricsCore counts the entries written to the outputs, it wraps
+// the outputs directly so that the entries dropped by the cores
+// outside, e.g. deduplication, are not counted.
+type metricsCore struct {
+	core    zapcore.Core
+	metrics Metrics
+}
+
+func (c *metricsCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *metricsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &metricsCore{core: c.core.With(fields), metrics: c.metrics}
+}
+
+func (c *metricsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.core.Check(ent, nil) == nil {
+		return ce
+	}
+
+	return ce.AddCore(ent, c)
+}
+
+func (c *metricsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.metrics.AddEntry(makeLevel(ent.Level), ent.LoggerName)
+	return c.core.Write(ent, fields)
+}
+
+func (c *metricsCore) Sync() error {
+	return c.core.Sync()
+}
+
+type entryKey struct {
+	level  Level
+	logger string
+}
+
+// CounterMetrics is a Metrics keeping the counts in memory, which are
+// served in Prometheus text format by Handler.
+type CounterMetrics struct {
+	mu      sync.Mutex
+	entries map[entryKey]uint64
+	bytes   map[string]uint64
+	dropped map[string]uint64
+}
+
+func NewCounterMetrics() *CounterMetrics {
+	return &CounterMetrics{
+		entries: map[entryKey]uint64{},
+		bytes:   map[string]uint64{},
+		dropped: map[string]uint64{},
+	}
+}
+
+func (m *CounterMetrics) AddEntry(lvl Level, logger string) {
+	m.mu.Lock()
+	m.entries[entryKey{level: lvl, logger: logger}]++
+	m.mu.Unlock()
+}
+
+func (m *CounterMetrics) AddBytes(sink string, n int) {
+	m.mu.Lock()
+	m.bytes[sink] += uint64(n)
+	m.mu.Unlock()
+}
+
+func (m *CounterMetrics) AddDropped(sink string, n uint64) {
+	m.mu.Lock()
+	m.dropped[sink] += n
+	m.mu.Unlock()
+}
+
+// Entries returns the number of entries of level lvl and logger.
+func (m *CounterMetrics) Entries(lvl Level, logger string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.entries[entryKey{level: lvl, logger: logger}]
+}
+
+// Bytes returns the bytes written to sink.
+func (m *CounterMetrics) Bytes(sink string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.bytes[sink]
+}
+
+// Dropped returns the entries dropped by sink.
+func (m *CounterMetrics) Dropped(sink string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.dropped[sink]
+}
+
+// Handler returns a http.Handler serving the counts in Prometheus text
+// format, as logx_entries_total, logx_bytes_total and logx_dropped_total.
+func (m *CounterMetrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(m.text()))
+	})
+}
+
+func (m *CounterMetrics) text() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sb := &strings.Builder{}
+	entries := make([]string, 0, len(m.entries))
+	for key, n := range m.entries {
+		entries = append(entries, fmt.Sprintf("logx_entries_total{level=%s,logger=%s} %d\n",
+			promLabel(key.level.String()), promLabel(key.logger), n))
+	}
+	writeMetric(sb, "logx_entries_total", "The number of log entries by level and logger.", entries)
+
+	writeMetric(sb, "logx_bytes_total", "The bytes of log written by sink.", sinkSamples("logx_bytes_total", m.bytes))
+	writeMetric(sb, "logx_dropped_total", "The number of log entries dropped by sink.",
+		sinkSamples("logx_dropped_total", m.dropped))
+
+	return sb.String()
+}
+
+func sinkSamples(name string, counts map[string]uint64) []string {
+	samples := make([]string, 0, len(counts))
+	for sink, n := range counts {
+		samples = append(samples, fmt.Sprintf("%s{sink=%s} %d\n", name, promLabel(sink), n))
+	}
+
+	return samples
+}
+
+func writeMetric(sb *strings.Builder, name, help string, samples []string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	sort.Strings(samples)
+	for _, sample := range samples {
+		sb.WriteString(sample)
+	}
+}
+
+// promLabel quotes label value, in which backslash, double quote and
+// newline are escaped.
+func promLabel(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
@@ -0,0 +1,118 @@
+package logx
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	dir := t.TempDir()
+	metrics := NewCounterMetrics()
+	lg, err := NewZapLogger(WithFile(dir, "app.log", 10, 0, 0, false),
+		WithSampling(1, 0, time.Minute), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Info("started")
+	lg.Logger().Info("started")
+	lg.Logger().Named("db").Error("failed")
+	lg.Logger().Named("db").Error("failed again")
+	lg.Close()
+
+	if n := metrics.Entries(LvlInfo, ""); n != 1 {
+		t.Fatalf("Expected 1 info entry after sampling, got: %d", n)
+	}
+
+	if n := metrics.Entries(LvlError, "db"); n != 2 {
+		t.Fatalf("Expected 2 error entries of db, got: %d", n)
+	}
+
+	stat, err := os.Stat(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := metrics.Bytes("file"); n != uint64(stat.Size()) {
+		t.Fatalf("Expected %d bytes of file, got: %d", stat.Size(), n)
+	}
+
+	metrics.AddDropped(`"net"`, 3)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, expected := range []string{
+		"# TYPE logx_entries_total counter\n",
+		`logx_entries_total{level="error",logger="db"} 2` + "\n",
+		`logx_entries_total{level="info",logger=""} 1` + "\n",
+		`logx_dropped_total{sink="\"net\""} 3` + "\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("Expected %q in metrics, got: %s", expected, body)
+		}
+	}
+}
+
+func TestMetricsDedupAndReload(t *testing.T) {
+	dir := t.TempDir()
+	metrics := NewCounterMetrics()
+	lg, err := NewZapLogger(WithFile(dir, "app.log", 10, 0, 0, false),
+		WithDedup(time.Minute), WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	for i := 0; i < 3; i++ {
+		lg.Logger().Warn("disk is slow")
+	}
+
+	if n := metrics.Entries(LvlWarn, ""); n != 1 {
+		t.Fatalf("Expected 1 warn entry after dedup, got: %d", n)
+	}
+
+	// the config loaded from file has no Metrics
+	if err := lg.Reload(&Config{Lvl: LvlDebug, Format: FormatJson, File: NewFileConfig(dir, "b.log", 10, 0, 0, false)}); err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Error("failed")
+	if n := metrics.Entries(LvlError, ""); n != 1 {
+		t.Fatalf("Expected 1 error entry after reload, got: %d", n)
+	}
+}
+
+func TestMetricsAsyncDropped(t *testing.T) {
+	metrics := NewCounterMetrics()
+	config := &Config{
+		Async:   &AsyncConfig{BufferSize: "1B", QueueSize: 1, DropPolicy: DropPolicyNewest},
+		Metrics: metrics,
+	}
+
+	gw := &gatedWriter{entered: make(chan struct{}), gate: make(chan struct{})}
+	ws, closer, err := config.wrapAsync("slow", gw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws.Write([]byte("entry\n"))
+	<-gw.entered
+	for i := 0; i < 9; i++ {
+		ws.Write([]byte("entry\n"))
+	}
+	close(gw.gate)
+	closer.Close()
+
+	async := ws.(*AsyncWriteSyncer)
+	if async.Dropped() == 0 || metrics.Dropped("slow") != async.Dropped() {
+		t.Fatalf("Expected %d dropped, got: %d", async.Dropped(), metrics.Dropped("slow"))
+	}
+
+	if written := 10 - async.Dropped(); metrics.Bytes("slow") != written*6 {
+		t.Fatalf("Expected %d bytes, got: %d", written*6, metrics.Bytes("slow"))
+	}
+}
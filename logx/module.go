@@ -0,0 +1,121 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// moduleLevels resolves levels of named loggers hierarchically, e.g.
+// "storage.fs" falls back to "storage", then to the logger level.
+type moduleLevels struct {
+	mu     sync.RWMutex
+	levels map[string]zapcore.Level
+	root   zap.AtomicLevel
+}
+
+func newModuleLevels(root zap.AtomicLevel) *moduleLevels {
+	return &moduleLevels{
+		levels: make(map[string]zapcore.Level),
+		root:   root,
+	}
+}
+
+func (m *moduleLevels) Level(name string) zapcore.Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name != "" {
+		if lvl, ok := m.levels[name]; ok {
+			return lvl
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return m.root.Level()
+}
+
+func (m *moduleLevels) Set(name string, lvl zapcore.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.levels[name] = lvl
+}
+
+func (m *moduleLevels) Unset(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.levels, name)
+}
+
+func (m *moduleLevels) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.levels = make(map[string]zapcore.Level)
+}
+
+// moduleEnabler is the level of a named logger.
+type moduleEnabler struct {
+	modules *moduleLevels
+	name    string
+}
+
+func (e moduleEnabler) Enabled(lvl zapcore.Level) bool {
+	return lvl >= e.modules.Level(e.name)
+}
+
+// Named returns the logger named by name, its level is set by
+// SetModuleLevel, or inherited from the parent, e.g. "storage" is the
+// parent of "storage.fs". The level of the root is the logger level.
+func (z *zapLogger) Named(name string) *zap.Logger {
+	if lg, ok := z.namedLoggers.Load(name); ok {
+		return lg.(*zap.Logger)
+	}
+
+	enabler := moduleEnabler{modules: z.modules, name: name}
+	lg := z.base.Named(name).WithOptions(zap.IncreaseLevel(enabler))
+	actual, _ := z.namedLoggers.LoadOrStore(name, lg)
+	return actual.(*zap.Logger)
+}
+
+// SNamed returns the sugared logger named by name.
+func (z *zapLogger) SNamed(name string) *zap.SugaredLogger {
+	return z.Named(name).Sugar()
+}
+
+// SetModuleLevel changes the level of the named logger and its
+// children which have no level of their own.
+func (z *zapLogger) SetModuleLevel(name string, lvl Level) error {
+	if name == "" {
+		return errors.New("module name cannot be empty")
+	}
+
+	zapLvl := makeZapLogLevel(lvl)
+	if zapLvl == zapcore.InvalidLevel {
+		return fmt.Errorf("invalid log level '%d' of module '%s'", lvl, name)
+	}
+
+	z.modules.Set(name, zapLvl)
+	return nil
+}
+
+// ResetModuleLevel makes the named logger inherit level from its parent.
+func (z *zapLogger) ResetModuleLevel(name string) {
+	z.modules.Unset(name)
+}
+
+// GetModuleLevel returns the effective level of the named logger.
+func (z *zapLogger) GetModuleLevel(name string) Level {
+	return makeLevel(z.modules.Level(name))
+}
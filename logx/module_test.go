@@ -0,0 +1,46 @@
+package logx
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNamed(t *testing.T) {
+	lg, err := InitZapLogger(&Config{
+		Lvl:      LvlInfo,
+		Format:   FormatJson,
+		OsStdout: true,
+		Modules:  map[string]Level{"storage": LvlDebug},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := lg.Named("storage.fs")
+	if !fs.Core().Enabled(zap.DebugLevel) {
+		t.Fatal("Expected storage.fs inherits debug level from storage")
+	}
+
+	if lg.Named("net").Core().Enabled(zap.DebugLevel) {
+		t.Fatal("Expected net inherits info level from root")
+	}
+
+	if err := lg.SetModuleLevel("storage.fs", LvlError); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs.Core().Enabled(zap.WarnLevel) {
+		t.Fatalf("Expected level: error, got: %s", lg.GetModuleLevel("storage.fs"))
+	}
+
+	lg.ResetModuleLevel("storage.fs")
+	lg.ResetModuleLevel("storage")
+	if err := lg.SetLevel(LvlWarn); err != nil {
+		t.Fatal(err)
+	}
+
+	if lg.GetModuleLevel("storage.fs") != LvlWarn {
+		t.Fatalf("Expected level: warn, got: %s", lg.GetModuleLevel("storage.fs"))
+	}
+}
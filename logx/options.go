@@ -2,6 +2,8 @@ package logx
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -25,10 +27,21 @@ type FileConfig struct {
 
 	// Along with lumberjack:
 	// https://github.com/natefinch/lumberjack/blob/v2.0/lumberjack.go
+	// MaxSize is in megabytes, 100 if it is 0, for both lumberjack
+	// and time based rotation.
 	MaxSize    int  `toml:"maxsize" json:"maxsize"`
 	MaxAge     int  `toml:"maxage" json:"maxage"`
 	MaxBackups int  `toml:"maxbackups" json:"maxbackups"`
 	Compress   bool `toml:"compress" json:"compress"`
+
+	// Time based rotation, it is "hourly", "daily" or a cron expression
+	// like "0 */6 * * *". Files are named by Pattern with %Y, %m, %d, %H,
+	// %M and %S, e.g. "app-%Y%m%d.log", and Name is a symlink to the
+	// latest file if LatestLink is set. Lumberjack is used if empty.
+	Rotation     string `toml:"rotation" json:"rotation"`
+	Pattern      string `toml:"pattern" json:"pattern"`
+	LatestLink   bool   `toml:"latest-link" json:"latest-link"`
+	MaxTotalSize string `toml:"max-total-size" json:"max-total-size"`
 }
 
 func NewFileConfig(path, name string, maxSize, maxAge, maxBackups int, compress bool) *FileConfig {
@@ -59,6 +72,33 @@ func (f *FileConfig) BuildLogger() (*lumberjack.Logger, error) {
 	}, nil
 }
 
+// BuildWriter builds the writer of log file, it rotates by time if
+// Rotation is set, otherwise by size with lumberjack.
+func (f *FileConfig) BuildWriter() (io.WriteCloser, error) {
+	if f.Rotation == "" {
+		lg, err := f.BuildLogger()
+		if err != nil {
+			return nil, err
+		}
+
+		return lg, nil
+	}
+
+	return newTimeRotator(f)
+}
+
+// maxSize returns MaxSize in megabytes, it is 100 if not set as
+// lumberjack does.
+func (f *FileConfig) maxSize() int {
+	if f.MaxSize == 0 {
+		return _defaultMaxSize
+	}
+
+	return f.MaxSize
+}
+
+const _defaultMaxSize = 100
+
 // Config represents log configurations in toml/json.
 type Config struct {
 	Lvl                 Level       `toml:"level" json:"level"`
@@ -70,6 +110,41 @@ type Config struct {
 	DisableCaller       bool        `toml:"disable-caller" json:"disable-caller"`
 	DisableStacktrace   bool        `toml:"disable-stacktrace" json:"disable-stacktrace"`
 	DisableErrorVerbose bool        `toml:"disable-error-verbose" json:"disable-error-verbose"`
+
+	// Color is "auto" (default), "always" or "never" for FormatDev, auto
+	// enables colors if stdout is a terminal and NO_COLOR is not set.
+	// Files are never colored, neither is stdout along with File.
+	Color string `toml:"color" json:"color"`
+
+	// Modules sets levels of named loggers, e.g. "storage" or "storage.fs",
+	// a named logger without level inherits from its parent.
+	Modules map[string]Level `toml:"modules" json:"modules"`
+
+	// Outputs are written along with File and OsStdout, each of
+	// them has its own levels and format.
+	Outputs []*OutputConfig `toml:"outputs" json:"outputs"`
+
+	// Async makes all the outputs write in background.
+	Async *AsyncConfig `toml:"async" json:"async"`
+
+	// Sampling and Dedup limit the entries of the same message.
+	Sampling *SamplingConfig `toml:"sampling" json:"sampling"`
+	Dedup    *DedupConfig    `toml:"dedup" json:"dedup"`
+
+	// Redact masks the secrets in messages and fields.
+	Redact *RedactConfig `toml:"redact" json:"redact"`
+
+	// Process attaches the fields of process to every entry.
+	Process *ProcessConfig `toml:"process" json:"process"`
+
+	// Metrics counts the entries, bytes and drops if it is set.
+	Metrics Metrics `toml:"-" json:"-"`
+
+	// external replaces the outputs, e.g. by WithSlogHandler.
+	external zapcore.Core
+	// consoleSet is set by WithConsole and WithoutConsole, otherwise
+	// NewZapLogger writes to stdout only if there is no other output.
+	consoleSet bool
 }
 
 func NewDefaultConfig() *Config {
@@ -92,6 +167,7 @@ func (c *Config) BuildZapEncoderConfig() zapcore.EncoderConfig {
 		EncodeLevel:    zapcore.CapitalLevelEncoder,
 		EncodeTime:     makeTimeEncoder,
 		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
 	if c.DisableTimestamp {
@@ -101,50 +177,189 @@ func (c *Config) BuildZapEncoderConfig() zapcore.EncoderConfig {
 	return encConfig
 }
 
-func (c *Config) BuildZapEncoder() zapcore.Encoder {
+func (c *Config) BuildZapEncoder() (zapcore.Encoder, error) {
+	return c.buildZapEncoder(c.Format, c.terminal())
+}
+
+// terminal returns stdout if it is the only sink of the core built by
+// Config rather than Outputs, so that colors are not written to File.
+func (c *Config) terminal() *os.File {
+	if c.OsStdout && (c.File == nil || len(c.File.Name) == 0) {
+		return os.Stdout
+	}
+
+	return nil
+}
+
+// buildZapEncoder builds the encoder of format writing to out, which
+// is nil if it is not stdout or stderr.
+func (c *Config) buildZapEncoder(format string, out *os.File) (zapcore.Encoder, error) {
 	config := c.BuildZapEncoderConfig()
-	switch c.Format {
+	switch format {
 	case FormatJson:
-		return zapcore.NewJSONEncoder(config)
+		return zapcore.NewJSONEncoder(config), nil
 	case FormatConsole:
-		return zapcore.NewConsoleEncoder(config)
+		return zapcore.NewConsoleEncoder(config), nil
 	case FormatText:
-		// TODO:
-		panic("it is still in todo list")
+		return NewLogfmtEncoder(config), nil
+	case FormatDev:
+		return NewDevEncoder(config, colorEnabled(c.Color, out)), nil
 	default:
-		panic("invalid format")
+		return nil, fmt.Errorf("invalid log format '%s'", format)
+	}
+}
+
+func isValidFormat(format string) bool {
+	switch format {
+	case FormatJson, FormatConsole, FormatText, FormatDev:
+		return true
+	default:
+		return false
 	}
 }
 
 func (c *Config) BuildZapWriteSyncer() ([]zapcore.WriteSyncer, error) {
+	syncers, _, err := c.buildZapWriteSyncer()
+	return syncers, err
+}
+
+// buildZapWriteSyncer also returns the files to close when
+// the logger is reloaded.
+func (c *Config) buildZapWriteSyncer() ([]zapcore.WriteSyncer, []io.Closer, error) {
 	syncers := []zapcore.WriteSyncer{}
+	closers := []io.Closer{}
 	if c.File != nil && len(c.File.Name) > 0 {
-		// build lumberjack logger
-		lg, err := c.File.BuildLogger()
+		// build lumberjack logger or time based rotator
+		w, err := c.File.BuildWriter()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		syncers = append(syncers, zapcore.AddSync(lg))
+		lg := newGuardedWriter(w)
+		ws, closer, err := c.wrapAsync("file", lg, lg)
+		if err != nil {
+			lg.Close()
+			return nil, nil, err
+		}
+
+		syncers = append(syncers, ws)
+		closers = append(closers, closer)
 	}
 
 	if c.OsStdout {
-		syncers = append(syncers, zapcore.AddSync(os.Stdout))
+		ws, closer, err := c.wrapAsync("stdout", zapcore.AddSync(os.Stdout), nil)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, err
+		}
+
+		syncers = append(syncers, ws)
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+
+	return syncers, closers, nil
+}
+
+// buildZapCore builds the core writing to all the outputs, the level
+// of logger is applied on top of it.
+func (c *Config) buildZapCore() (zapcore.Core, []io.Closer, error) {
+	if c.external != nil {
+		core, err := c.wrapCore(c.external)
+		return core, nil, err
+	}
+
+	syncers, closers, err := c.buildZapWriteSyncer()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cores := []zapcore.Core{}
+	if len(syncers) > 0 {
+		encoder, err := c.BuildZapEncoder()
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, err
+		}
+
+		cores = append(cores, zapcore.NewCore(
+			encoder,
+			zap.CombineWriteSyncers(syncers...),
+			zap.NewAtomicLevelAt(zap.DebugLevel),
+		))
 	}
 
-	return syncers, nil
+	for _, output := range c.Outputs {
+		core, closer, err := output.buildZapCore(c)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, err
+		}
+
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+		cores = append(cores, core)
+	}
+
+	core, err := c.wrapCore(newOutputsCore(cores))
+	if err != nil {
+		closeAll(closers)
+		return nil, nil, err
+	}
+
+	return core, closers, nil
+}
+
+// wrapCore applies redaction, deduplication and then sampling to core,
+// the entries written to core are counted to Metrics.
+func (c *Config) wrapCore(core zapcore.Core) (zapcore.Core, error) {
+	if c.Metrics != nil {
+		core = &metricsCore{core: core, metrics: c.Metrics}
+	}
+
+	var err error
+	if c.Redact != nil {
+		if core, err = c.Redact.wrapCore(core); err != nil {
+			return nil, err
+		}
+	}
+
+	// outside of redaction, which wraps the errors in fields
+	if c.DisableErrorVerbose {
+		core = &noVerboseCore{core: core}
+	}
+
+	if c.Dedup != nil {
+		if core, err = c.Dedup.wrapCore(core); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Sampling != nil {
+		if core, err = c.Sampling.wrapCore(core); err != nil {
+			return nil, err
+		}
+	}
+
+	return core, nil
+}
+
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
 }
 
 func (c *Config) BuildZapOptions() []zap.Option {
 	opts := []zap.Option{}
 
-	stackLevel := zap.ErrorLevel
 	if c.Development {
 		opts = append(opts, zap.Development())
-		stackLevel = zap.WarnLevel
 	}
 
-	if !c.DisableStacktrace {
+	if stackLevel := c.stackLevel(); stackLevel != nil {
 		opts = append(opts, zap.AddStacktrace(stackLevel))
 	}
 
@@ -152,9 +367,26 @@ func (c *Config) BuildZapOptions() []zap.Option {
 		opts = append(opts, zap.AddCaller())
 	}
 
+	if c.Process != nil {
+		opts = append(opts, zap.Fields(c.Process.fields()...))
+	}
+
 	return opts
 }
 
+// stackLevel returns the levels with stacktraces, nil if disabled.
+func (c *Config) stackLevel() zapcore.LevelEnabler {
+	if c.DisableStacktrace {
+		return nil
+	}
+
+	if c.Development {
+		return zap.WarnLevel
+	}
+
+	return zap.ErrorLevel
+}
+
 func WithLevel(lvl Level) configurer {
 	return func(c *Config) {
 		c.Lvl = lvl
@@ -167,9 +399,103 @@ func WithFile(path, name string, maxSize, maxAge, maxBackups int, compress bool)
 	}
 }
 
+func WithModuleLevel(name string, lvl Level) configurer {
+	return func(c *Config) {
+		if c.Modules == nil {
+			c.Modules = make(map[string]Level)
+		}
+
+		c.Modules[name] = lvl
+	}
+}
+
+// WithRotation rotates the log file by time, see FileConfig.Rotation.
+func WithRotation(rotation, pattern string, latestLink bool) configurer {
+	return func(c *Config) {
+		if c.File == nil {
+			c.File = &FileConfig{}
+		}
+
+		c.File.Rotation = rotation
+		c.File.Pattern = pattern
+		c.File.LatestLink = latestLink
+	}
+}
+
+func WithOutput(output *OutputConfig) configurer {
+	return func(c *Config) {
+		c.Outputs = append(c.Outputs, output)
+	}
+}
+
+// WithAsync writes in background with a queue of queueSize entries,
+// policy is one of DropPolicyBlock, DropPolicyOldest and DropPolicyNewest.
+func WithAsync(bufferSize string, flushInterval time.Duration, queueSize int, policy string) configurer {
+	return func(c *Config) {
+		c.Async = &AsyncConfig{
+			BufferSize:    bufferSize,
+			FlushInterval: flushInterval.String(),
+			QueueSize:     queueSize,
+			DropPolicy:    policy,
+		}
+	}
+}
+
+// WithSampling samples debug, info and warn entries, see SamplingConfig.
+func WithSampling(initial, thereafter int, tick time.Duration) configurer {
+	return func(c *Config) {
+		c.Sampling = &SamplingConfig{
+			Initial:    initial,
+			Thereafter: thereafter,
+			Tick:       tick.String(),
+		}
+	}
+}
+
+// WithDedup deduplicates debug, info and warn entries in window.
+func WithDedup(window time.Duration) configurer {
+	return func(c *Config) {
+		c.Dedup = &DedupConfig{Window: window.String()}
+	}
+}
+
+// WithRedact masks the values of keys along with the default ones,
+// see RedactConfig.
+func WithRedact(keys ...string) configurer {
+	return func(c *Config) {
+		c.Redact = &RedactConfig{Keys: keys}
+	}
+}
+
+// WithProcess attaches service, version, environment, hostname and
+// pid to every entry, version is from build info if it is empty.
+func WithProcess(service, version, environment string) configurer {
+	return func(c *Config) {
+		c.Process = &ProcessConfig{
+			Service:     service,
+			Version:     version,
+			Environment: environment,
+			Hostname:    true,
+			Pid:         true,
+		}
+	}
+}
+
+func WithMetrics(metrics Metrics) configurer {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
 func WithConsole() configurer {
 	return func(c *Config) {
-		c.OsStdout = true
+		c.OsStdout, c.consoleSet = true, true
+	}
+}
+
+func WithoutConsole() configurer {
+	return func(c *Config) {
+		c.OsStdout, c.consoleSet = false, true
 	}
 }
 
@@ -179,6 +505,12 @@ func WithFormat(format string) configurer {
 	}
 }
 
+func WithColor(mode string) configurer {
+	return func(c *Config) {
+		c.Color = mode
+	}
+}
+
 func WithDisableTimestamp() configurer {
 	return func(c *Config) {
 		c.DisableTimestamp = true
@@ -0,0 +1,289 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	OutputStdout   = "stdout"
+	OutputStderr   = "stderr"
+	OutputFile     = "file"
+	OutputSyslog   = "syslog"
+	OutputJournald = "journald"
+	OutputNetwork  = "network"
+)
+
+// OutputConfig represents a named output in toml/json, entries from
+// MinLevel (e.g. "error") to MaxLevel (e.g. "fatal") are written to it.
+type OutputConfig struct {
+	Name     string          `toml:"name" json:"name"`
+	Type     string          `toml:"type" json:"type"`
+	File     *FileConfig     `toml:"file" json:"file"`
+	Syslog   *SyslogConfig   `toml:"syslog" json:"syslog"`
+	Journald *JournaldConfig `toml:"journald" json:"journald"`
+	Ship     *ShipConfig     `toml:"ship" json:"ship"`
+	MinLevel *Level          `toml:"min-level" json:"min-level"`
+	MaxLevel *Level          `toml:"max-level" json:"max-level"`
+	// Format overrides the format of Config if not empty.
+	Format string `toml:"format" json:"format"`
+}
+
+// levelEnabler returns the levels between MinLevel and MaxLevel.
+func (o *OutputConfig) levelEnabler() zapcore.LevelEnabler {
+	min, max := zapcore.DebugLevel, zapcore.FatalLevel
+	if o.MinLevel != nil {
+		min = makeZapLogLevel(*o.MinLevel)
+	}
+
+	if o.MaxLevel != nil {
+		max = makeZapLogLevel(*o.MaxLevel)
+	}
+
+	return zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl >= min && lvl <= max
+	})
+}
+
+func (o *OutputConfig) buildWriteSyncer() (zapcore.WriteSyncer, io.Closer, error) {
+	switch o.Type {
+	case OutputStdout:
+		return zapcore.AddSync(os.Stdout), nil, nil
+	case OutputStderr:
+		return zapcore.AddSync(os.Stderr), nil, nil
+	case OutputFile:
+		if o.File == nil {
+			return nil, nil, fmt.Errorf("no file of output '%s'", o.Name)
+		}
+
+		w, err := o.File.BuildWriter()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lg := newGuardedWriter(w)
+		return lg, lg, nil
+	case OutputNetwork:
+		if o.Ship == nil {
+			return nil, nil, fmt.Errorf("no ship of output '%s'", o.Name)
+		}
+
+		s, err := NewShipper(o.Ship)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s.name = o.Name
+		return s, s, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid type '%s' of output '%s'", o.Type, o.Name)
+	}
+}
+
+// terminal returns the file of stdout or stderr output, it is nil for
+// the other outputs not to be colored.
+func (o *OutputConfig) terminal() *os.File {
+	switch o.Type {
+	case OutputStdout:
+		return os.Stdout
+	case OutputStderr:
+		return os.Stderr
+	default:
+		return nil
+	}
+}
+
+// sink returns the name of output in metrics, which is its type
+// if it has no name.
+func (o *OutputConfig) sink() string {
+	if o.Name != "" {
+		return o.Name
+	}
+
+	return o.Type
+}
+
+func (o *OutputConfig) buildZapCore(c *Config) (zapcore.Core, io.Closer, error) {
+	format := o.Format
+	if format == "" {
+		format = c.Format
+	}
+
+	encoder, err := c.buildZapEncoder(format, o.terminal())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch o.Type {
+	case OutputSyslog:
+		config := o.Syslog
+		if config == nil {
+			config = &SyslogConfig{}
+		}
+
+		conn, err := newSyslogConn(config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ws, closer, err := c.wrapAsync(o.sink(), conn, conn)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+
+		return newSyslogCore(config, encoder, ws, o.levelEnabler()), closer, nil
+	case OutputJournald:
+		config := o.Journald
+		if config == nil {
+			config = &JournaldConfig{}
+		}
+
+		conn, err := newJournalConn(config)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ws, closer, err := c.wrapAsync(o.sink(), conn, conn)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+
+		return newJournaldCore(config, ws, o.levelEnabler()), closer, nil
+	}
+
+	syncer, closer, err := o.buildWriteSyncer()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s, ok := syncer.(*Shipper); ok {
+		s.meter = c.meter(o.sink())
+	}
+
+	ws, asyncCloser, err := c.wrapAsync(o.sink(), syncer, closer)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, nil, err
+	}
+
+	return zapcore.NewCore(encoder, ws, o.levelEnabler()), asyncCloser, nil
+}
+
+func (o *OutputConfig) validate(key string) []error {
+	errs := []error{}
+	switch o.Type {
+	case OutputStdout, OutputStderr:
+	case OutputFile:
+		if o.File == nil || len(o.File.Name) == 0 {
+			errs = append(errs, &ConfigError{Key: key + ".file", Err: errors.New("no file name is configured")})
+		} else {
+			errs = append(errs, o.File.validate(key+".file")...)
+		}
+	case OutputSyslog:
+		if o.Syslog != nil {
+			errs = append(errs, o.Syslog.validate(key+".syslog")...)
+		}
+	case OutputJournald:
+	case OutputNetwork:
+		if o.Ship == nil {
+			errs = append(errs, &ConfigError{Key: key + ".ship", Err: errors.New("no ship is configured")})
+		} else {
+			errs = append(errs, o.Ship.validate(key+".ship")...)
+		}
+	default:
+		errs = append(errs, &ConfigError{Key: key + ".type", Err: fmt.Errorf("invalid output type '%s'", o.Type)})
+	}
+
+	if o.MinLevel != nil && makeZapLogLevel(*o.MinLevel) == zapcore.InvalidLevel {
+		errs = append(errs, &ConfigError{Key: key + ".min-level", Err: fmt.Errorf("invalid log level '%d'", *o.MinLevel)})
+	}
+
+	if o.MaxLevel != nil && makeZapLogLevel(*o.MaxLevel) == zapcore.InvalidLevel {
+		errs = append(errs, &ConfigError{Key: key + ".max-level", Err: fmt.Errorf("invalid log level '%d'", *o.MaxLevel)})
+	}
+
+	if o.MinLevel != nil && o.MaxLevel != nil && *o.MinLevel < *o.MaxLevel {
+		errs = append(errs, &ConfigError{Key: key + ".min-level", Err: fmt.Errorf("min level '%s' is above max level '%s'", o.MinLevel, o.MaxLevel)})
+	}
+
+	if o.Format != "" && !isValidFormat(o.Format) {
+		errs = append(errs, &ConfigError{Key: key + ".format", Err: fmt.Errorf("invalid log format '%s'", o.Format)})
+	}
+
+	return errs
+}
+
+// outputsCore is the tee of outputs, unlike zapcore.NewTee its Write
+// only writes to the outputs enabling the level, so that the cores
+// wrapping it can write to it directly and return the errors.
+type outputsCore []zapcore.Core
+
+func newOutputsCore(cores []zapcore.Core) zapcore.Core {
+	if len(cores) == 1 {
+		return cores[0]
+	}
+
+	return outputsCore(cores)
+}
+
+func (c outputsCore) Enabled(lvl zapcore.Level) bool {
+	for _, core := range c {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c outputsCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := make(outputsCore, len(c))
+	for i, core := range c {
+		clone[i] = core.With(fields)
+	}
+
+	return clone
+}
+
+func (c outputsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	for _, core := range c {
+		ce = core.Check(ent, ce)
+	}
+
+	return ce
+}
+
+func (c outputsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var errs []error
+	for _, core := range c {
+		if !core.Enabled(ent.Level) {
+			continue
+		}
+
+		if err := core.Write(ent, fields); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c outputsCore) Sync() error {
+	var errs []error
+	for _, core := range c {
+		if err := core.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
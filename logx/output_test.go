@@ -0,0 +1,76 @@
+package logx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.toml")
+	content := `
+level = 6
+format = "json"
+os-stdout = false
+
+[[outputs]]
+name = "errors"
+type = "file"
+min-level = "error"
+file = { path = "` + dir + `", name = "error.log", maxsize = 10 }
+
+[[outputs]]
+name = "info"
+type = "file"
+min-level = "info"
+max-level = "warn"
+format = "text"
+file = { path = "` + dir + `", name = "info.log", maxsize = 10 }
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg, err := InitZapLogger(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Debug("debug message")
+	lg.Logger().Info("info message")
+	lg.Logger().Warn("warn message")
+	lg.Logger().Error("error message")
+	lg.Sync()
+
+	errLog, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	if !strings.Contains(string(errLog), `"message":"error message"`) ||
+		strings.Contains(string(errLog), "warn message") {
+		t.Fatalf("Expected error only, got: %s", errLog)
+	}
+
+	infoLog, _ := os.ReadFile(filepath.Join(dir, "info.log"))
+	if !strings.Contains(string(infoLog), `message="info message"`) ||
+		!strings.Contains(string(infoLog), `message="warn message"`) ||
+		strings.Contains(string(infoLog), "error message") ||
+		strings.Contains(string(infoLog), "debug message") {
+		t.Fatalf("Expected info and warn in logfmt, got: %s", infoLog)
+	}
+
+	errLvl, infoLvl := LvlError, LvlInfo
+	config.Outputs = append(config.Outputs, &OutputConfig{
+		Name:     "errors",
+		Type:     "socket",
+		MinLevel: &infoLvl,
+		MaxLevel: &errLvl,
+	})
+	if err := config.Validate(); err == nil {
+		t.Fatal("Unexpected valid outputs")
+	}
+}
@@ -0,0 +1,89 @@
+package logx
+
+import (
+	"os"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// ProcessConfig represents the static fields of process in toml/json,
+// which are attached to every entry. Version is filled from the build
+// info of binary if not set, Hostname and Pid add the ones of process.
+type ProcessConfig struct {
+	Service     string `toml:"service" json:"service"`
+	Version     string `toml:"version" json:"version"`
+	Environment string `toml:"environment" json:"environment"`
+	Hostname    bool   `toml:"hostname" json:"hostname"`
+	Pid         bool   `toml:"pid" json:"pid"`
+}
+
+func (p *ProcessConfig) fields() []zap.Field {
+	fields := []zap.Field{}
+	if p.Service != "" {
+		fields = append(fields, zap.String("service", p.Service))
+	}
+
+	version := p.Version
+	if version == "" {
+		version = buildVersion()
+	}
+
+	if version != "" {
+		fields = append(fields, zap.String("version", version))
+	}
+
+	if p.Environment != "" {
+		fields = append(fields, zap.String("environment", p.Environment))
+	}
+
+	if p.Hostname {
+		if hostname, err := os.Hostname(); err == nil {
+			fields = append(fields, zap.String("hostname", hostname))
+		}
+	}
+
+	if p.Pid {
+		fields = append(fields, zap.Int("pid", os.Getpid()))
+	}
+
+	return fields
+}
+
+// buildVersion returns the version of the binary.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	return versionOf(info)
+}
+
+// versionOf returns the version of main module, or the vcs revision
+// if it is built from a work tree, e.g. "3f2a9c1d8e7b-dirty".
+func versionOf(info *debug.BuildInfo) string {
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+
+	return revision
+}
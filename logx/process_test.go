@@ -0,0 +1,47 @@
+package logx
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+)
+
+func TestProcessFields(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewZapLogger(WithFile(dir, "app.log", 10, 0, 0, false),
+		WithProcess("billing", "v1.2.3", "staging"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lg.Logger().Info("started")
+	lg.Logger().Named("db").Warn("slow")
+	lg.Close()
+
+	hostname, _ := os.Hostname()
+	for _, entry := range readEntries(t, filepath.Join(dir, "app.log")) {
+		if entry["service"] != "billing" || entry["version"] != "v1.2.3" || entry["environment"] != "staging" ||
+			entry["hostname"] != hostname || entry["pid"] != float64(os.Getpid()) {
+			t.Fatalf("Expected process fields, got: %v", entry)
+		}
+	}
+}
+
+func TestProcessBuildVersion(t *testing.T) {
+	revision := debug.BuildSetting{Key: "vcs.revision", Value: "3f2a9c1d8e7b5a4c3d2e1f0a9b8c7d6e5f4a3b2c"}
+	for _, c := range []struct {
+		info     *debug.BuildInfo
+		expected string
+	}{
+		{&debug.BuildInfo{Main: debug.Module{Version: "v1.2.3"}, Settings: []debug.BuildSetting{revision}}, "v1.2.3"},
+		{&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{revision}}, "3f2a9c1d8e7b"},
+		{&debug.BuildInfo{Settings: []debug.BuildSetting{revision, {Key: "vcs.modified", Value: "true"}}}, "3f2a9c1d8e7b-dirty"},
+		{&debug.BuildInfo{Settings: []debug.BuildSetting{{Key: "vcs.modified", Value: "true"}}}, ""},
+		{&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, ""},
+	} {
+		if version := versionOf(c.info); version != c.expected {
+			t.Fatalf("Expected version %q, got: %q", c.expected, version)
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const _defaultMask = "***"
+
+var (
+	// the keys redacted by default, they are case insensitive.
+	_defaultRedactKeys = []string{
+		"password", "passwd", "secret", "token", "access_token",
+		"refresh_token", "api_key", "apikey", "authorization", "cookie",
+	}
+
+	// the bearer tokens.
+	_defaultRedactPatterns = []string{
+		`(?i)bearer\s+[a-z0-9\-._~+/]+=*`,
+	}
+
+	// the card-like numbers of 13 to 19 digits, only the ones passing
+	// the Luhn check are redacted, rather than timestamps or IDs.
+	_cardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// Secret is a string always written as masked, e.g.
+// zap.Any("pin", logx.Secret(pin)) or Infow("", "pin", logx.Secret(pin)).
+type Secret string
+
+func (s Secret) String() string {
+	return _defaultMask
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(_defaultMask), nil
+}
+
+// SecretString constructs a field of secret value.
+func SecretString(key, value string) zap.Field {
+	return zap.Stringer(key, Secret(value))
+}
+
+// RedactConfig represents redaction in toml/json, the values of Keys and
+// the strings matching Patterns are replaced with Mask. They are added
+// to the default keys like password and token, and the default patterns
+// of bearer tokens and card numbers.
+type RedactConfig struct {
+	Keys     []string `toml:"keys" json:"keys"`
+	Patterns []string `toml:"patterns" json:"patterns"`
+	Mask     string   `toml:"mask" json:"mask"`
+}
+
+func (r *RedactConfig) validate(key string) []error {
+	errs := []error{}
+	for _, k := range r.Keys {
+		if k == "" {
+			errs = append(errs, &ConfigError{Key: key + ".keys", Err: errors.New("key cannot be empty")})
+		}
+	}
+
+	for _, pattern := range r.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, &ConfigError{Key: key + ".patterns", Err: err})
+		}
+	}
+
+	return errs
+}
+
+func (r *RedactConfig) build() (*redactor, error) {
+	rd := &redactor{keys: map[string]bool{}, mask: r.Mask}
+	if rd.mask == "" {
+		rd.mask = _defaultMask
+	}
+
+	for _, key := range append(_defaultRedactKeys, r.Keys...) {
+		rd.keys[strings.ToLower(key)] = true
+	}
+
+	for _, pattern := range append(_defaultRedactPatterns, r.Patterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern '%s': %w", pattern, err)
+		}
+		rd.patterns = append(rd.patterns, re)
+	}
+
+	return rd, nil
+}
+
+func (r *RedactConfig) wrapCore(core zapcore.Core) (zapcore.Core, error) {
+	rd, err := r.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &redactCore{core: core, r: rd}, nil
+}
+
+type redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	mask     string
+}
+
+func (r *redactor) isSecretKey(key string) bool {
+	return r.keys[strings.ToLower(key)]
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, r.mask)
+	}
+
+	return _cardPattern.ReplaceAllStringFunc(s, func(number string) string {
+		if isLuhnValid(number) {
+			return r.mask
+		}
+
+		return number
+	})
+}
+
+// isLuhnValid checks the digits of number by Luhn, the separators
+// of space and dash are skipped.
+func isLuhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = r.field(field)
+	}
+
+	return redacted
+}
+
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	}
+
+	if r.isSecretKey(f.Key) {
+		return zap.String(f.Key, r.mask)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.redactString(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			f.Interface = []byte(r.redactString(string(b)))
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			if _, ok := s.(Secret); !ok {
+				f.Interface = &redactStringer{s: s, r: r}
+			}
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			f.Interface = &redactError{err: err, r: r}
+		}
+	case zapcore.ObjectMarshalerType, zapcore.InlineMarshalerType:
+		if m, ok := f.Interface.(zapcore.ObjectMarshaler); ok {
+			f.Interface = &redactObject{m: m, r: r}
+		}
+	case zapcore.ArrayMarshalerType:
+		if m, ok := f.Interface.(zapcore.ArrayMarshaler); ok {
+			f.Interface = &redactArray{m: m, r: r}
+		}
+	case zapcore.ReflectType:
+		f.Interface = r.reflected(f.Interface)
+	}
+
+	return f
+}
+
+// reflected redacts the value through its json form, the value is
+// kept if it cannot be converted. The numbers are kept as json.Number,
+// so that the large integers are not rounded by float64.
+func (r *redactor) reflected(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return v
+	}
+
+	return r.value(generic)
+}
+
+func (r *redactor) value(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.redactString(value)
+	case map[string]interface{}:
+		for key, elem := range value {
+			if r.isSecretKey(key) {
+				value[key] = r.mask
+			} else {
+				value[key] = r.value(elem)
+			}
+		}
+	case []interface{}:
+		for i, elem := range value {
+			value[i] = r.value(elem)
+		}
+	}
+
+	return v
+}
+
+type redactStringer struct {
+	s fmt.Stringer
+	r *redactor
+}
+
+func (s *redactStringer) String() string {
+	return s.r.redactString(s.s.String())
+}
+
+type redactError struct {
+	err error
+	r   *redactor
+}
+
+func (e *redactError) Error() string {
+	return e.r.redactString(e.err.Error())
+}
+
+func (e *redactError) Unwrap() error {
+	return e.err
+}
+
+// Format keeps the verbose form of err, e.g. with stack.
+func (e *redactError) Format(s fmt.State, verb rune) {
+	if formatter, ok := e.err.(fmt.Formatter); ok && verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, e.r.redactString(fmt.Sprintf("%+v", formatter)))
+		return
+	}
+
+	fmt.Fprint(s, e.Error())
+}
+
+type redactObject struct {
+	m zapcore.ObjectMarshaler
+	r *redactor
+}
+
+func (o *redactObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.m.MarshalLogObject(&redactObjectEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+type redactArray struct {
+	m zapcore.ArrayMarshaler
+	r *redactor
+}
+
+func (a *redactArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return a.m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// redactObjectEncoder redacts the nested objects, the values of secret
+// keys and the strings.
+type redactObjectEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+// masked adds the mask as the value of key if it is secret.
+func (e *redactObjectEncoder) masked(key string) bool {
+	if !e.r.isSecretKey(key) {
+		return false
+	}
+
+	e.ObjectEncoder.AddString(key, e.r.mask)
+	return true
+}
+
+func (e *redactObjectEncoder) AddArray(key string, m zapcore.ArrayMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+
+	return e.ObjectEncoder.AddArray(key, &redactArray{m: m, r: e.r})
+}
+
+func (e *redactObjectEncoder) AddObject(key string, m zapcore.ObjectMarshaler) error {
+	if e.masked(key) {
+		return nil
+	}
+
+	return e.ObjectEncoder.AddObject(key, &redactObject{m: m, r: e.r})
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *redactObjectEncoder) AddString(key, value string) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddString(key, e.r.redactString(value))
+	}
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, value interface{}) error {
+	if e.masked(key) {
+		return nil
+	}
+
+	return e.ObjectEncoder.AddReflected(key, e.r.reflected(value))
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, value []byte) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddBinary(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddBool(key string, value bool) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddBool(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex128(key string, value complex128) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddComplex128(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex64(key string, value complex64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddComplex64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddDuration(key string, value time.Duration) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddDuration(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, value float64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddFloat64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat32(key string, value float32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddFloat32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt(key string, value int) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, value int64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt32(key string, value int32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt16(key string, value int16) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt16(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt8(key string, value int8) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddInt8(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddTime(key string, value time.Time) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddTime(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint(key string, value uint) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, value uint64) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint64(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint32(key string, value uint32) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint32(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint16(key string, value uint16) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint16(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint8(key string, value uint8) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUint8(key, value)
+	}
+}
+
+func (e *redactObjectEncoder) AddUintptr(key string, value uintptr) {
+	if !e.masked(key) {
+		e.ObjectEncoder.AddUintptr(key, value)
+	}
+}
+
+type redactArrayEncoder struct {
+	zapcore.ArrayEncoder
+	r *redactor
+}
+
+func (e *redactArrayEncoder) AppendArray(m zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(&redactArray{m: m, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendObject(m zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(&redactObject{m: m, r: e.r})
+}
+
+func (e *redactArrayEncoder) AppendByteString(value []byte) {
+	e.ArrayEncoder.AppendString(e.r.redactString(string(value)))
+}
+
+func (e *redactArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.r.redactString(value))
+}
+
+func (e *redactArrayEncoder) AppendReflected(value interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.reflected(value))
+}
+
+// redactCore redacts the message and fields before they are
+// written to core.
+type redactCore struct {
+	core zapcore.Core
+	r    *redactor
+}
+
+// NewRedactCore wraps core to redact the entries by config,
+// the defaults are used if config is nil. The entries are written
+// by Write of core, which should skip the outputs not enabling the
+// level, unlike a zapcore.NewTee of cores of different levels.
+func NewRedactCore(core zapcore.Core, config *RedactConfig) (zapcore.Core, error) {
+	if config == nil {
+		config = &RedactConfig{}
+	}
+
+	return config.wrapCore(core)
+}
+
+func (c *redactCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{core: c.core.With(c.r.fields(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.core.Check(ent, nil) == nil {
+		return ce
+	}
+
+	return ce.AddCore(ent, c)
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.redactString(ent.Message)
+	return c.core.Write(ent, c.r.fields(fields))
+}
+
+func (c *redactCore) Sync() error {
+	return c.core.Sync()
+}
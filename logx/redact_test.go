@@ -0,0 +1,88 @@
+package logx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type failedWriter struct{}
+
+func (failedWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk is full")
+}
+
+func TestRedact(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewZapLogger(
+		WithFile(dir, "app.log", 10, 0, 0, false),
+		WithRedact("pin"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("name", "alice")
+		enc.AddString("Password", "hunter2")
+		return enc.AddObject("card", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("number", "4111 1111 1111 1111")
+			enc.AddInt64("pin", 8642)
+			enc.AddBinary("token", []byte("raw-token"))
+			enc.AddFloat64("Secret", 3.14159)
+			return nil
+		}))
+	})
+
+	lg.Logger().With(zap.String("token", "abc123")).Info(
+		"request with Authorization: Bearer eyJhbGciOi.J9",
+		zap.String("pin", "1234"),
+		zap.Object("user", user),
+		zap.Any("headers", map[string]interface{}{"Cookie": "session=42", "accept": "*/*"}),
+		zap.Error(errors.New("charge 5500-0000-0000-0004 failed")),
+		zap.Any("otp", Secret("987654")),
+		SecretString("code", "s3cr3t"),
+		zap.Any("order", map[string]interface{}{"id": int64(9007199254740993)}),
+		zap.String("at", "created at 1729350000123"),
+	)
+	lg.Close()
+
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	for _, leaked := range []string{"abc123", "eyJhbGciOi", "1234", "hunter2", "4111", "session=42", "5500", "987654", "s3cr3t",
+		"8642", "cmF3LXRva2Vu", "3.14159"} {
+		if strings.Contains(string(content), leaked) {
+			t.Fatalf("Expected %s redacted, got: %s", leaked, content)
+		}
+	}
+
+	for _, kept := range []string{`"name":"alice"`, `"accept":"*/*"`, `"otp":"***"`, `"pin":"***"`,
+		`"id":9007199254740993`, "1729350000123"} {
+		if !strings.Contains(string(content), kept) {
+			t.Fatalf("Expected %s, got: %s", kept, content)
+		}
+	}
+
+	core, err := NewRedactCore(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(failedWriter{}),
+		zapcore.DebugLevel,
+	), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := core.Write(zapcore.Entry{Message: "lost"}, nil); err == nil {
+		t.Fatal("Expected the error of writing")
+	}
+
+	config := NewDefaultConfig()
+	config.Redact = &RedactConfig{Patterns: []string{"("}}
+	if err := config.Validate(); err == nil {
+		t.Fatal("Unexpected valid redact patterns")
+	}
+}
@@ -0,0 +1,241 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const _defaultWatchInterval = 5 * time.Second
+
+var errOutputClosed = errors.New("output is closed")
+
+type coreVersion struct {
+	core zapcore.Core
+	gen  uint64
+}
+
+type coreHolder struct {
+	current atomic.Value // *coreVersion
+}
+
+func (h *coreHolder) load() *coreVersion {
+	return h.current.Load().(*coreVersion)
+}
+
+// reloadableCore delegates to the core held by holder, so that the
+// core of loggers already handed out can be swapped. The context
+// fields are applied to the new core lazily.
+type reloadableCore struct {
+	holder  *coreHolder
+	fields  []zapcore.Field
+	derived atomic.Value // *coreVersion
+}
+
+func newReloadableCore(core zapcore.Core) *reloadableCore {
+	holder := &coreHolder{}
+	holder.current.Store(&coreVersion{core: core})
+	return &reloadableCore{holder: holder}
+}
+
+// swap replaces the core and returns the old one.
+func (c *reloadableCore) swap(core zapcore.Core) zapcore.Core {
+	old := c.holder.load()
+	c.holder.current.Store(&coreVersion{core: core, gen: old.gen + 1})
+	return old.core
+}
+
+func (c *reloadableCore) current() zapcore.Core {
+	base := c.holder.load()
+	if len(c.fields) == 0 {
+		return base.core
+	}
+
+	if derived, ok := c.derived.Load().(*coreVersion); ok && derived.gen == base.gen {
+		return derived.core
+	}
+
+	derived := &coreVersion{core: base.core.With(c.fields), gen: base.gen}
+	c.derived.Store(derived)
+	return derived.core
+}
+
+// Enabled reports all the levels, since the outputs may be swapped by
+// Reload to the ones of other levels, entries are filtered by Check.
+func (c *reloadableCore) Enabled(lvl zapcore.Level) bool {
+	return true
+}
+
+func (c *reloadableCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &reloadableCore{holder: c.holder, fields: merged}
+}
+
+func (c *reloadableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.current().Check(ent, ce)
+}
+
+func (c *reloadableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *reloadableCore) Sync() error {
+	return c.current().Sync()
+}
+
+// guardedWriter rejects the writes once it is closed. The entries
+// checked before Reload may be written to the old outputs after they
+// are closed, which would reopen the files otherwise.
+type guardedWriter struct {
+	mu     sync.RWMutex
+	w      io.WriteCloser
+	closed bool
+}
+
+func newGuardedWriter(w io.WriteCloser) *guardedWriter {
+	return &guardedWriter{w: w}
+}
+
+func (g *guardedWriter) Write(p []byte) (int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.closed {
+		return 0, errOutputClosed
+	}
+
+	return g.w.Write(p)
+}
+
+func (g *guardedWriter) Sync() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if s, ok := g.w.(interface{ Sync() error }); ok && !g.closed {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+// Close waits for the writes in progress and closes the writer.
+func (g *guardedWriter) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+
+	g.closed = true
+	return g.w.Close()
+}
+
+// Reload applies level, modules, format and outputs of config to
+// the running logger, including the loggers already handed out.
+// Caller, stacktrace, development options and the fields of Process
+// are only applied when the logger is initialized. The Metrics and
+// the handler of WithSlogHandler of the running logger are kept if
+// config has none, e.g. the one loaded from file.
+func (z *zapLogger) Reload(config *Config) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	kept := *config
+	if kept.Metrics == nil {
+		kept.Metrics = z.metrics
+	}
+	if kept.external == nil {
+		kept.external = z.external
+	}
+	config = &kept
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	core, closers, err := config.buildZapCore()
+	if err != nil {
+		return err
+	}
+	z.metrics, z.external = config.Metrics, config.external
+
+	old := z.core.swap(core)
+	old.Sync()
+	// the writes in progress are waited, and the ones of entries checked
+	// before the swap are rejected rather than reopening the outputs
+	closeAll(z.closers)
+	z.closers = closers
+
+	z.level.SetLevel(makeZapLogLevel(config.Lvl))
+	z.modules.Reset()
+	for name, lvl := range config.Modules {
+		z.modules.Set(name, makeZapLogLevel(lvl))
+	}
+
+	return nil
+}
+
+// WatchConfig checks the config file at path every interval (5s if
+// it is not positive), and reloads the logger when it is modified,
+// until ctx is done. Errors of loading or applying the config are
+// passed to onError if not nil.
+func (z *zapLogger) WatchConfig(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = _defaultWatchInterval
+	}
+
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+
+	var modTime time.Time
+	var size int64
+	if stat, err := os.Stat(path); err == nil {
+		modTime, size = stat.ModTime(), stat.Size()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			stat, err := os.Stat(path)
+			if err != nil {
+				report(err)
+				continue
+			}
+
+			if stat.ModTime().Equal(modTime) && stat.Size() == size {
+				continue
+			}
+			modTime, size = stat.ModTime(), stat.Size()
+
+			config, err := LoadConfig(path)
+			if err != nil {
+				report(err)
+				continue
+			}
+
+			if err := z.Reload(config); err != nil {
+				report(err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,592 @@
+package logx
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RotateHourly = "hourly"
+	RotateDaily  = "daily"
+)
+
+// rotateSchedule tells when the next rotation happens.
+type rotateSchedule interface {
+	Next(t time.Time) time.Time
+}
+
+type hourlySchedule struct{}
+
+func (hourlySchedule) Next(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+}
+
+type dailySchedule struct{}
+
+func (dailySchedule) Next(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+// parseRotation parses "hourly", "daily" or a cron expression with
+// five fields: minute, hour, day of month, month and day of week.
+func parseRotation(s string) (rotateSchedule, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case RotateHourly, "@hourly":
+		return hourlySchedule{}, nil
+	case RotateDaily, "@daily", "@midnight":
+		return dailySchedule{}, nil
+	default:
+		return parseCron(s)
+	}
+}
+
+// cronSchedule is a classic cron expression, each field is a bitmap
+// of the matched values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+var _cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(_cronFields) {
+		return nil, fmt.Errorf("invalid rotation '%s', expected hourly, daily or cron expression", expr)
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		b, err := parseCronField(field, _cronFields[i].min, _cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of cron '%s': %v", _cronFields[i].name, expr, err)
+		}
+		bits[i] = b
+	}
+
+	// 7 is also sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step '%s'", part)
+			}
+			step, part = n, part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			r := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(r[0])
+			hi, err2 = strconv.Atoi(r[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range '%s'", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", part)
+			}
+			lo, hi = n, n
+		}
+
+		// day of week accepts 7 as sunday
+		upper := max
+		if max == 6 {
+			upper = 7
+		}
+
+		if lo < min || hi > upper || lo > hi {
+			return 0, fmt.Errorf("'%s' out of range [%d, %d]", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// give up after 5 years, e.g. Feb 30th never happens
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// formatPattern replaces %Y, %m, %d, %H, %M, %S and %% in pattern
+// with the time.
+func formatPattern(pattern string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(pattern[i])
+		}
+	}
+
+	return b.String()
+}
+
+// globPattern converts pattern to glob matching all the files
+// created by the rotator, including the sequence and gzip suffix.
+func globPattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '%' && i+1 < len(pattern) && pattern[i+1] != '%' {
+			b.WriteByte('*')
+			i++
+			continue
+		}
+
+		if pattern[i] == '%' && i+1 < len(pattern) {
+			i++
+		}
+
+		switch pattern[i] {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(pattern[i])
+	}
+
+	b.WriteByte('*')
+	return b.String()
+}
+
+func defaultPattern(name, rotation string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	switch strings.ToLower(rotation) {
+	case RotateDaily, "@daily", "@midnight":
+		return base + "-%Y%m%d" + ext
+	case RotateHourly, "@hourly":
+		return base + "-%Y%m%d%H" + ext
+	default:
+		return base + "-%Y%m%d%H%M" + ext
+	}
+}
+
+// timeRotator writes to files named by pattern with time, and starts
+// a new file by schedule or when the file exceeds maxSize.
+type timeRotator struct {
+	dir        string
+	pattern    string
+	link       string
+	schedule   rotateSchedule
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	maxTotal   int64
+	compress   bool
+	now        func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	filename string
+	size     int64
+	next     time.Time
+
+	millOnce sync.Once
+	millCh   chan struct{}
+	millWg   sync.WaitGroup
+}
+
+func newTimeRotator(f *FileConfig) (*timeRotator, error) {
+	schedule, err := parseRotation(f.Rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := f.Pattern
+	if pattern == "" {
+		pattern = defaultPattern(f.Name, f.Rotation)
+	}
+
+	r := &timeRotator{
+		dir:        f.Path,
+		pattern:    pattern,
+		schedule:   schedule,
+		maxSize:    int64(f.maxSize()) * 1024 * 1024,
+		maxBackups: f.MaxBackups,
+		maxAge:     time.Duration(f.MaxAge) * 24 * time.Hour,
+		compress:   f.Compress,
+		now:        time.Now,
+	}
+
+	if f.LatestLink {
+		r.link = filepath.Join(f.Path, f.Name)
+	}
+
+	if f.MaxTotalSize != "" {
+		total, err := parseSize(f.MaxTotalSize)
+		if err != nil {
+			return nil, err
+		}
+		r.maxTotal = total
+	}
+
+	return r, nil
+}
+
+func (r *timeRotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if r.file == nil || !now.Before(r.next) {
+		if err := r.rotate(now); err != nil {
+			return 0, err
+		}
+	} else if r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *timeRotator) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Sync()
+}
+
+// Close closes the current file and waits for the background
+// compression and cleanup.
+func (r *timeRotator) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+
+	if r.millCh != nil {
+		close(r.millCh)
+		r.millCh = nil
+	}
+	r.mu.Unlock()
+
+	r.millWg.Wait()
+	return err
+}
+
+// rotate opens the file for now, with a sequence suffix if the file
+// of the same name is already full.
+func (r *timeRotator) rotate(now time.Time) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return err
+	}
+
+	base := filepath.Join(r.dir, formatPattern(r.pattern, now))
+	filename := base
+	var size int64
+	for seq := 1; ; seq++ {
+		stat, err := os.Stat(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// reuse the file of the current period if it has space
+		if filename != r.filename && (r.maxSize <= 0 || stat.Size() < r.maxSize) {
+			size = stat.Size()
+			break
+		}
+
+		filename = base + "." + strconv.Itoa(seq)
+	}
+
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if r.file != nil {
+		r.file.Close()
+	}
+
+	r.file, r.filename, r.size = file, filename, size
+	r.next = r.schedule.Next(now)
+	if r.next.IsZero() {
+		r.next = now.AddDate(100, 0, 0)
+	}
+
+	if r.link != "" {
+		if err := updateLink(filename, r.link); err != nil {
+			return err
+		}
+	}
+
+	r.startMill()
+	return nil
+}
+
+// updateLink points link to target atomically.
+func updateLink(target, link string) error {
+	if stat, err := os.Lstat(link); err == nil && stat.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("'%s' exists and is not a symlink", link)
+	}
+
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Base(target), tmp); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, link)
+}
+
+func (r *timeRotator) startMill() {
+	r.millOnce.Do(func() {
+		r.millCh = make(chan struct{}, 1)
+		r.millWg.Add(1)
+		go func(ch chan struct{}) {
+			defer r.millWg.Done()
+			for range ch {
+				r.mill()
+			}
+		}(r.millCh)
+	})
+
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+	}
+}
+
+type rotatedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// mill compresses the rotated files and removes the ones exceeding
+// backups, age or total size limits. The files are listed under the
+// lock, so that the file opened by a rotation later is not included.
+func (r *timeRotator) mill() {
+	r.mu.Lock()
+	current := r.filename
+	files := r.rotatedFiles(current)
+	r.mu.Unlock()
+
+	if r.compress {
+		for i, f := range files {
+			if strings.HasSuffix(f.path, ".gz") || r.isCurrent(f.path) {
+				continue
+			}
+
+			if size, err := compressFile(f.path); err == nil {
+				files[i].path, files[i].size = f.path+".gz", size
+			}
+		}
+	}
+
+	total := int64(0)
+	if stat, err := os.Stat(current); err == nil {
+		total = stat.Size()
+	}
+
+	now := r.now()
+	for i, f := range files {
+		total += f.size
+		remove := (r.maxBackups > 0 && i >= r.maxBackups) ||
+			(r.maxAge > 0 && now.Sub(f.modTime) > r.maxAge) ||
+			(r.maxTotal > 0 && total > r.maxTotal)
+		if remove && !r.isCurrent(f.path) {
+			os.Remove(f.path)
+		}
+	}
+}
+
+// isCurrent returns if path is the file written now, which may be
+// reopened by rotation in the same period.
+func (r *timeRotator) isCurrent(path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return path == r.filename
+}
+
+// rotatedFiles returns the files created by the rotator except the
+// current one, the newest first.
+func (r *timeRotator) rotatedFiles(current string) []rotatedFile {
+	paths, _ := filepath.Glob(filepath.Join(r.dir, globPattern(r.pattern)))
+
+	files := []rotatedFile{}
+	for _, path := range paths {
+		if path == current || path == r.link || strings.HasSuffix(path, ".tmp") {
+			continue
+		}
+
+		stat, err := os.Lstat(path)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+
+		files = append(files, rotatedFile{path: path, size: stat.Size(), modTime: stat.ModTime()})
+	}
+
+	// names with time pattern break the tie of modification time
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].modTime.Equal(files[j].modTime) {
+			return files[i].path > files[j].path
+		}
+
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	return files
+}
+
+func compressFile(path string) (int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stat.Mode())
+	if err != nil {
+		return 0, err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(path + ".gz")
+		return 0, err
+	}
+
+	// keep the time of the rotated file for age based retention
+	os.Chtimes(path+".gz", stat.ModTime(), stat.ModTime())
+	os.Remove(path)
+
+	gzStat, err := os.Stat(path + ".gz")
+	if err != nil {
+		return 0, err
+	}
+
+	return gzStat.Size(), nil
+}
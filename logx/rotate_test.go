@@ -0,0 +1,73 @@
+package logx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	schedule, err := parseRotation("30 */6 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday
+	now := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+	expected := time.Date(2024, 3, 4, 0, 30, 0, 0, time.UTC)
+	if next := schedule.Next(now); !next.Equal(expected) {
+		t.Fatalf("Expected next: %s, got: %s", expected, next)
+	}
+
+	if _, err := parseRotation("61 * * * *"); err == nil {
+		t.Fatal("Unexpected cron with minute 61")
+	}
+}
+
+func TestTimeRotator(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newTimeRotator(&FileConfig{
+		Path:       dir,
+		Name:       "app.log",
+		MaxBackups: 1,
+		Rotation:   RotateDaily,
+		LatestLink: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.maxSize != 100*1024*1024 {
+		t.Fatalf("Expected max size of 100MB as lumberjack, got: %d", r.maxSize)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.AddDate(0, 0, 1)
+	}
+
+	// waits for the background compression and cleanup
+	r.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "app-*.log*"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files with 1 backup, got: %v", files)
+	}
+
+	target, err := os.Readlink(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if target != "app-20240303.log" {
+		t.Fatalf("Expected latest: app-20240303.log, got: %s", target)
+	}
+}
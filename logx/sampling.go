@@ -0,0 +1,330 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const _repeatedKey = "repeated"
+
+// the levels sampled and deduplicated by default, so that
+// errors are always written.
+var _defaultLimitedLevels = []Level{LvlDebug, LvlInfo, LvlWarn}
+
+// SamplingConfig represents zap sampling in toml/json, the first Initial
+// entries with the same level and message are written every Tick (e.g.
+// "1s"), and then every Thereafter entry. Only Levels are sampled,
+// which are debug, info and warn by default.
+type SamplingConfig struct {
+	Initial    int     `toml:"initial" json:"initial"`
+	Thereafter int     `toml:"thereafter" json:"thereafter"`
+	Tick       string  `toml:"tick" json:"tick"`
+	Levels     []Level `toml:"levels" json:"levels"`
+}
+
+// DedupConfig represents deduplication in toml/json, the entries with
+// the same level, logger name and message are written once every Window
+// (e.g. "10s"), along with the number of entries dropped before as
+// "repeated". Only Levels are deduplicated, which are debug, info and
+// warn by default.
+type DedupConfig struct {
+	Window string  `toml:"window" json:"window"`
+	Levels []Level `toml:"levels" json:"levels"`
+}
+
+func parsePositiveDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	return d, nil
+}
+
+func validateLevels(key string, levels []Level) []error {
+	errs := []error{}
+	for _, lvl := range levels {
+		if makeZapLogLevel(lvl) == zapcore.InvalidLevel {
+			errs = append(errs, &ConfigError{Key: key, Err: fmt.Errorf("invalid log level '%d'", lvl)})
+		}
+	}
+
+	return errs
+}
+
+// makeLevelsEnabler enables levels, or the default ones if it is empty.
+func makeLevelsEnabler(levels []Level) zapcore.LevelEnabler {
+	if len(levels) == 0 {
+		levels = _defaultLimitedLevels
+	}
+
+	enabled := map[zapcore.Level]bool{}
+	for _, lvl := range levels {
+		enabled[makeZapLogLevel(lvl)] = true
+	}
+
+	return zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return enabled[lvl]
+	})
+}
+
+func (s *SamplingConfig) validate(key string) []error {
+	errs := []error{}
+	if s.Initial < 1 {
+		errs = append(errs, &ConfigError{Key: key + ".initial", Err: fmt.Errorf("initial must be positive, got '%d'", s.Initial)})
+	}
+
+	if s.Thereafter < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".thereafter", Err: fmt.Errorf("negative thereafter '%d'", s.Thereafter)})
+	}
+
+	if _, err := parsePositiveDuration(s.Tick, time.Second); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".tick", Err: err})
+	}
+
+	return append(errs, validateLevels(key+".levels", s.Levels)...)
+}
+
+func (s *SamplingConfig) wrapCore(core zapcore.Core) (zapcore.Core, error) {
+	tick, err := parsePositiveDuration(s.Tick, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	sampled := zapcore.NewSamplerWithOptions(core, tick, s.Initial, s.Thereafter)
+	return newLevelsCore(sampled, core, makeLevelsEnabler(s.Levels)), nil
+}
+
+func (d *DedupConfig) validate(key string) []error {
+	errs := []error{}
+	if _, err := parsePositiveDuration(d.Window, 0); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".window", Err: err})
+	} else if d.Window == "" {
+		errs = append(errs, &ConfigError{Key: key + ".window", Err: errors.New("no window is configured")})
+	}
+
+	return append(errs, validateLevels(key+".levels", d.Levels)...)
+}
+
+func (d *DedupConfig) wrapCore(core zapcore.Core) (zapcore.Core, error) {
+	window, err := parsePositiveDuration(d.Window, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if window == 0 {
+		return nil, errors.New("no window is configured")
+	}
+
+	return NewDedupCore(core, window, d.Levels...), nil
+}
+
+// levelsCore routes the entries of levels to limited, and the
+// others to core.
+type levelsCore struct {
+	limited zapcore.Core
+	core    zapcore.Core
+	levels  zapcore.LevelEnabler
+}
+
+func newLevelsCore(limited, core zapcore.Core, levels zapcore.LevelEnabler) zapcore.Core {
+	return &levelsCore{limited: limited, core: core, levels: levels}
+}
+
+func (c *levelsCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *levelsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelsCore{
+		limited: c.limited.With(fields),
+		core:    c.core.With(fields),
+		levels:  c.levels,
+	}
+}
+
+func (c *levelsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.levels.Enabled(ent.Level) {
+		return c.limited.Check(ent, ce)
+	}
+
+	return c.core.Check(ent, ce)
+}
+
+func (c *levelsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.levels.Enabled(ent.Level) {
+		return c.limited.Write(ent, fields)
+	}
+
+	return c.core.Write(ent, fields)
+}
+
+func (c *levelsCore) Sync() error {
+	return c.core.Sync()
+}
+
+type dedupKey struct {
+	level   zapcore.Level
+	name    string
+	message string
+}
+
+// dedupRecord is the window of a message, and the last entry
+// dropped in it.
+type dedupRecord struct {
+	start  time.Time
+	count  int
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+type dedupState struct {
+	window    time.Duration
+	mu        sync.Mutex
+	records   map[dedupKey]*dedupRecord
+	lastSweep time.Time
+}
+
+// dedupCore drops the repeated messages in a window, the last one
+// dropped is written with the number as "repeated" after the window.
+type dedupCore struct {
+	core   zapcore.Core
+	levels zapcore.LevelEnabler
+	state  *dedupState
+}
+
+// NewDedupCore wraps core to write the entries with the same level,
+// logger name and message once every window, levels are debug, info
+// and warn if not specified.
+func NewDedupCore(core zapcore.Core, window time.Duration, levels ...Level) zapcore.Core {
+	return &dedupCore{
+		core:   core,
+		levels: makeLevelsEnabler(levels),
+		state: &dedupState{
+			window:  window,
+			records: map[dedupKey]*dedupRecord{},
+		},
+	}
+}
+
+func (c *dedupCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{core: c.core.With(fields), levels: c.levels, state: c.state}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Enabled(ent.Level) {
+		return c.core.Check(ent, ce)
+	}
+
+	if c.core.Check(ent, nil) == nil {
+		return ce
+	}
+
+	return ce.AddCore(ent, c)
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	s := c.state
+	key := dedupKey{level: ent.Level, name: ent.LoggerName, message: ent.Message}
+
+	s.mu.Lock()
+	pending := s.sweep(ent.Time)
+
+	record, ok := s.records[key]
+	if ok && ent.Time.Sub(record.start) < s.window {
+		record.count++
+		record.core, record.ent, record.fields = c.core, ent, fields
+		s.mu.Unlock()
+
+		return writeEntries(pending)
+	}
+
+	if ok && record.count > 0 {
+		pending = append(pending, record.repeated())
+	}
+	s.records[key] = &dedupRecord{start: ent.Time}
+	s.mu.Unlock()
+
+	return writeEntries(append(pending, &dedupRecord{core: c.core, ent: ent, fields: fields}))
+}
+
+// Sync writes the entries dropped in the windows not ended yet.
+func (c *dedupCore) Sync() error {
+	s := c.state
+	s.mu.Lock()
+	pending := []*dedupRecord{}
+	for key, record := range s.records {
+		if record.count > 0 {
+			pending = append(pending, record.repeated())
+		}
+		delete(s.records, key)
+	}
+	s.mu.Unlock()
+
+	err := writeEntries(pending)
+	return errors.Join(err, c.core.Sync())
+}
+
+// sweep removes the ended windows once every window, and returns the
+// entries dropped in them.
+func (s *dedupState) sweep(now time.Time) []*dedupRecord {
+	if now.Sub(s.lastSweep) < s.window {
+		return nil
+	}
+	s.lastSweep = now
+
+	pending := []*dedupRecord{}
+	for key, record := range s.records {
+		if now.Sub(record.start) < s.window {
+			continue
+		}
+
+		if record.count > 0 {
+			pending = append(pending, record.repeated())
+		}
+		delete(s.records, key)
+	}
+
+	return pending
+}
+
+// repeated returns the last entry dropped with the number of dropped.
+func (r *dedupRecord) repeated() *dedupRecord {
+	fields := append(r.fields[:len(r.fields):len(r.fields)], zap.Int(_repeatedKey, r.count))
+	return &dedupRecord{core: r.core, ent: r.ent, fields: fields}
+}
+
+// writeEntries writes records to their cores, the levels of outputs
+// are applied by the cores, see outputsCore.
+func writeEntries(records []*dedupRecord) error {
+	var errs []error
+	for _, record := range records {
+		if !record.core.Enabled(record.ent.Level) {
+			continue
+		}
+
+		if err := record.core.Write(record.ent, record.fields); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+package logx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDedupCore(t *testing.T) {
+	buf := &bytes.Buffer{}
+	inner := zapcore.NewCore(
+		zapcore.NewJSONEncoder(NewDefaultConfig().BuildZapEncoderConfig()),
+		zapcore.AddSync(buf),
+		zapcore.DebugLevel,
+	)
+	core := NewDedupCore(inner, time.Minute)
+
+	write := func(lvl zapcore.Level, msg string, at time.Time) {
+		ent := zapcore.Entry{Level: lvl, Message: msg, Time: at}
+		if ce := core.Check(ent, nil); ce != nil {
+			ce.Write()
+		}
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		write(zapcore.WarnLevel, "flapping", start.Add(time.Duration(i)*time.Second))
+		write(zapcore.ErrorLevel, "failed", start.Add(time.Duration(i)*time.Second))
+	}
+
+	if n := strings.Count(buf.String(), "flapping"); n != 1 {
+		t.Fatalf("Expected 1 warning, got: %d", n)
+	}
+
+	if n := strings.Count(buf.String(), "failed"); n != 5 {
+		t.Fatalf("Expected 5 errors, got: %d", n)
+	}
+
+	buf.Reset()
+	write(zapcore.WarnLevel, "flapping", start.Add(2*time.Minute))
+	if n := strings.Count(buf.String(), "flapping"); n != 2 ||
+		!strings.Contains(buf.String(), `"repeated":4`) {
+		t.Fatalf("Expected repeated warning and new one, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	write(zapcore.WarnLevel, "flapping", start.Add(2*time.Minute+time.Second))
+	if buf.Len() != 0 {
+		t.Fatalf("Expected warning dropped, got: %s", buf.String())
+	}
+
+	core.Sync()
+	if !strings.Contains(buf.String(), `"repeated":1`) {
+		t.Fatalf("Expected repeated warning on sync, got: %s", buf.String())
+	}
+}
+
+func TestDedupCoreWriteError(t *testing.T) {
+	core := NewDedupCore(zapcore.NewCore(
+		zapcore.NewJSONEncoder(NewDefaultConfig().BuildZapEncoderConfig()),
+		zapcore.AddSync(failedWriter{}),
+		zapcore.DebugLevel,
+	), time.Minute)
+
+	start := time.Now()
+	if err := core.Write(zapcore.Entry{Level: zapcore.WarnLevel, Message: "lost", Time: start}, nil); err == nil {
+		t.Fatal("Expected write error, got: nil")
+	}
+
+	// the repeated entry is written on sync
+	core.Write(zapcore.Entry{Level: zapcore.WarnLevel, Message: "lost", Time: start.Add(time.Second)}, nil)
+	if err := core.Sync(); err == nil {
+		t.Fatal("Expected write error on sync, got: nil")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := NewZapLogger(
+		WithFile(dir, "app.log", 10, 0, 0, false),
+		WithSampling(2, 0, time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		lg.Logger().Info("sampled")
+		lg.Logger().Error("not sampled")
+	}
+	lg.Close()
+
+	content, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if n := strings.Count(string(content), `"sampled"`); n != 2 {
+		t.Fatalf("Expected 2 info entries, got: %d", n)
+	}
+
+	if n := strings.Count(string(content), `"not sampled"`); n != 10 {
+		t.Fatalf("Expected 10 error entries, got: %d", n)
+	}
+
+	config := NewDefaultConfig()
+	config.Sampling = &SamplingConfig{Initial: 0}
+	config.Dedup = &DedupConfig{}
+	if err := config.Validate(); err == nil {
+		t.Fatal("Unexpected valid sampling and dedup")
+	}
+}
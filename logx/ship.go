@@ -0,0 +1,730 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	_defaultShipBatchSize     = 100
+	_defaultShipBatchInterval = time.Second
+	_defaultShipMaxRetries    = 3
+	_defaultShipMinBackoff    = 100 * time.Millisecond
+	_defaultShipMaxBackoff    = 30 * time.Second
+	_defaultShipTimeout       = 10 * time.Second
+
+	// the batches kept in memory at most, while the batches
+	// before are being retried
+	_maxShipPendingBatches = 16
+
+	_spillExt = ".log"
+)
+
+// ShipConfig represents shipping logs to a collector in toml/json, URL
+// is like "http://collector:8080/logs" or "tcp://collector:5170".
+//
+// Entries are sent in batches of BatchSize, or every BatchInterval (e.g.
+// "1s"), a batch is retried MaxRetries times with backoff from MinBackoff
+// to MaxBackoff. The batches failed are spilled to SpillDir up to
+// SpillLimit (e.g. "100MiB") dropping the oldest ones, and replayed once
+// the collector is back. They are dropped if SpillDir is empty.
+// The shippers of the same SpillDir in a process share it, e.g. the ones
+// rebuilt by Reload, while SpillDir should not be used by other processes.
+type ShipConfig struct {
+	URL           string `toml:"url" json:"url"`
+	BatchSize     int    `toml:"batch-size" json:"batch-size"`
+	BatchInterval string `toml:"batch-interval" json:"batch-interval"`
+	MaxRetries    int    `toml:"max-retries" json:"max-retries"`
+	MinBackoff    string `toml:"min-backoff" json:"min-backoff"`
+	MaxBackoff    string `toml:"max-backoff" json:"max-backoff"`
+	Timeout       string `toml:"timeout" json:"timeout"`
+	SpillDir      string `toml:"spill-dir" json:"spill-dir"`
+	SpillLimit    string `toml:"spill-limit" json:"spill-limit"`
+}
+
+func (s *ShipConfig) parseURL() (*url.URL, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("no host of '%s'", s.URL)
+		}
+	default:
+		return nil, fmt.Errorf("invalid url '%s'", s.URL)
+	}
+
+	return u, nil
+}
+
+func (s *ShipConfig) validate(key string) []error {
+	errs := []error{}
+	if _, err := s.parseURL(); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".url", Err: err})
+	}
+
+	if s.BatchSize < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".batch-size", Err: fmt.Errorf("negative batch size '%d'", s.BatchSize)})
+	}
+
+	if s.MaxRetries < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".max-retries", Err: fmt.Errorf("negative retries '%d'", s.MaxRetries)})
+	}
+
+	durations := []struct {
+		key, value string
+	}{
+		{"batch-interval", s.BatchInterval},
+		{"min-backoff", s.MinBackoff},
+		{"max-backoff", s.MaxBackoff},
+		{"timeout", s.Timeout},
+	}
+	for _, d := range durations {
+		if _, err := parsePositiveDuration(d.value, time.Second); err != nil {
+			errs = append(errs, &ConfigError{Key: key + "." + d.key, Err: err})
+		}
+	}
+
+	if s.SpillLimit != "" {
+		if _, err := parseSize(s.SpillLimit); err != nil {
+			errs = append(errs, &ConfigError{Key: key + ".spill-limit", Err: err})
+		}
+	}
+
+	if s.SpillDir != "" {
+		if err := checkWritableDir(s.SpillDir); err != nil {
+			errs = append(errs, &ConfigError{Key: key + ".spill-dir", Err: err})
+		}
+	}
+
+	return errs
+}
+
+var errShipRejected = errors.New("batch is rejected by collector")
+
+// ShipStats represents the delivery of a Shipper, in entries.
+type ShipStats struct {
+	Name      string
+	Sent      uint64
+	Failed    uint64 // failed attempts of sending
+	Spilled   uint64
+	Replayed  uint64
+	Dropped   uint64
+	SpillSize int64 // bytes of the batches spilled
+}
+
+// Shipper is a zapcore.WriteSyncer sending entries to a collector in
+// background, see ShipConfig.
+type Shipper struct {
+	name       string
+	send       func([]byte) error
+	batchSize  int
+	interval   time.Duration
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	spill      *spillDir
+	tcp        *tcpSender
+
+	mu      sync.Mutex
+	pending bytes.Buffer
+	count   int
+	closed  bool
+
+	// used by the background goroutine only
+	failures    int
+	nextAttempt time.Time
+
+	wake    chan struct{}
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	sent     uint64
+	failed   uint64
+	spilled  uint64
+	replayed uint64
+	dropped  uint64
+	meter    meter
+}
+
+// NewShipper starts shipping the entries written to it, the batches
+// spilled before are replayed.
+func NewShipper(config *ShipConfig) (*Shipper, error) {
+	u, err := config.parseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Shipper{
+		batchSize:  config.BatchSize,
+		maxRetries: config.MaxRetries,
+		wake:       make(chan struct{}, 1),
+		flushes:    make(chan chan struct{}),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	if s.batchSize == 0 {
+		s.batchSize = _defaultShipBatchSize
+	}
+	if s.maxRetries == 0 {
+		s.maxRetries = _defaultShipMaxRetries
+	}
+
+	if s.interval, err = parsePositiveDuration(config.BatchInterval, _defaultShipBatchInterval); err != nil {
+		return nil, err
+	}
+	if s.minBackoff, err = parsePositiveDuration(config.MinBackoff, _defaultShipMinBackoff); err != nil {
+		return nil, err
+	}
+	if s.maxBackoff, err = parsePositiveDuration(config.MaxBackoff, _defaultShipMaxBackoff); err != nil {
+		return nil, err
+	}
+
+	timeout, err := parsePositiveDuration(config.Timeout, _defaultShipTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "tcp" {
+		s.tcp = &tcpSender{address: u.Host, timeout: timeout}
+		s.send = s.tcp.send
+	} else {
+		sender := &httpSender{url: u.String(), client: &http.Client{Timeout: timeout}}
+		s.send = sender.send
+	}
+
+	if config.SpillDir != "" {
+		var limit int64
+		if config.SpillLimit != "" {
+			if limit, err = parseSize(config.SpillLimit); err != nil {
+				return nil, err
+			}
+		}
+
+		if s.spill, err = openSpillDir(config.SpillDir, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Write adds a copy of p to the batch, the entries are expected to
+// end with a newline as the ones of zap encoders. The entry is dropped
+// if too many entries are waiting for the batches being retried.
+func (s *Shipper) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, errors.New("shipper is closed")
+	}
+
+	if s.count >= s.batchSize*_maxShipPendingBatches {
+		s.drop(1)
+		return len(p), nil
+	}
+
+	s.pending.Write(p)
+	s.count++
+	if s.count >= s.batchSize {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync sends the batch, or spills it if the collector is down.
+func (s *Shipper) Sync() error {
+	reply := make(chan struct{})
+	select {
+	case s.flushes <- reply:
+		<-reply
+	case <-s.stopped:
+	}
+
+	return nil
+}
+
+// Close flushes the batch and stops shipping.
+func (s *Shipper) Close() error {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+
+		close(s.done)
+		<-s.stopped
+
+		if s.tcp != nil {
+			s.tcp.close()
+		}
+
+		if s.spill != nil {
+			s.spill.close()
+		}
+	})
+
+	return nil
+}
+
+// Stats returns the delivery so far.
+func (s *Shipper) Stats() ShipStats {
+	stats := ShipStats{
+		Name:     s.name,
+		Sent:     atomic.LoadUint64(&s.sent),
+		Failed:   atomic.LoadUint64(&s.failed),
+		Spilled:  atomic.LoadUint64(&s.spilled),
+		Replayed: atomic.LoadUint64(&s.replayed),
+		Dropped:  atomic.LoadUint64(&s.dropped),
+	}
+
+	if s.spill != nil {
+		stats.SpillSize = s.spill.Size()
+	}
+
+	return stats
+}
+
+func (s *Shipper) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.wake:
+			s.flush()
+		case <-ticker.C:
+			s.flush()
+		case reply := <-s.flushes:
+			s.flush()
+			close(reply)
+		case <-s.done:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush sends the batch after the spilled ones to keep the order,
+// and the batch is spilled if the collector is down.
+func (s *Shipper) flush() {
+	s.mu.Lock()
+	batch := append([]byte(nil), s.pending.Bytes()...)
+	count := s.count
+	s.pending.Reset()
+	s.count = 0
+	s.mu.Unlock()
+
+	if count > 0 {
+		if s.spill.Empty() && !time.Now().Before(s.nextAttempt) {
+			err := s.sendRetry(batch)
+			if err == nil {
+				atomic.AddUint64(&s.sent, uint64(count))
+				return
+			}
+
+			if errors.Is(err, errShipRejected) {
+				s.drop(uint64(count))
+				return
+			}
+		}
+
+		s.store(batch, count)
+	}
+
+	s.replay()
+}
+
+// sendRetry sends batch with backoff, and delays the next attempt
+// if it fails. The batch rejected is not retried.
+func (s *Shipper) sendRetry(batch []byte) error {
+	backoff := s.minBackoff
+	for retry := 0; ; retry++ {
+		err := s.send(batch)
+		if err == nil || errors.Is(err, errShipRejected) {
+			s.failures = 0
+			return err
+		}
+
+		atomic.AddUint64(&s.failed, 1)
+		if retry >= s.maxRetries || !s.sleep(backoff) {
+			s.failures++
+			s.nextAttempt = time.Now().Add(s.backoff(s.failures))
+			return err
+		}
+		backoff = s.nextBackoff(backoff)
+	}
+}
+
+// replay sends the spilled batches from the oldest, until one fails.
+// The segments cannot be read are dropped.
+func (s *Shipper) replay() {
+	for s.spill != nil && !time.Now().Before(s.nextAttempt) {
+		segment, data, count, ok, err := s.spill.Claim()
+		if !ok {
+			return
+		}
+
+		entries := uint64(count)
+		if err != nil {
+			s.spill.Remove(segment)
+			s.drop(entries)
+			continue
+		}
+
+		err = s.send(data)
+		if errors.Is(err, errShipRejected) {
+			s.spill.Remove(segment)
+			s.drop(entries)
+			continue
+		}
+
+		if err != nil {
+			s.spill.Release(segment)
+			atomic.AddUint64(&s.failed, 1)
+			s.failures++
+			s.nextAttempt = time.Now().Add(s.backoff(s.failures))
+			return
+		}
+
+		s.failures = 0
+		s.spill.Remove(segment)
+		atomic.AddUint64(&s.replayed, entries)
+	}
+}
+
+func (s *Shipper) store(batch []byte, count int) {
+	if s.spill == nil {
+		s.drop(uint64(count))
+		return
+	}
+
+	dropped, err := s.spill.Append(batch, count)
+	if err != nil {
+		s.drop(uint64(count))
+		return
+	}
+
+	atomic.AddUint64(&s.spilled, uint64(count))
+	s.drop(uint64(dropped))
+}
+
+func (s *Shipper) drop(n uint64) {
+	atomic.AddUint64(&s.dropped, n)
+	s.meter.addDropped(n)
+}
+
+// sleep returns false if the shipper is closed.
+func (s *Shipper) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Shipper) nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+
+	return d
+}
+
+// backoff returns the delay after failures of batches.
+func (s *Shipper) backoff(failures int) time.Duration {
+	d := s.minBackoff
+	for i := 1; i < failures && d < s.maxBackoff; i++ {
+		d = s.nextBackoff(d)
+	}
+
+	return d
+}
+
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+// send posts entries as newline delimited body, the batch is accepted
+// by 2xx, and rejected by 4xx except 408 and 429 since retrying does
+// not help.
+func (h *httpSender) send(batch []byte) error {
+	resp, err := h.client.Post(h.url, "application/x-ndjson", bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w with status '%s'", errShipRejected, resp.Status)
+	default:
+		return fmt.Errorf("unexpected status '%s'", resp.Status)
+	}
+}
+
+type tcpSender struct {
+	address string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func (t *tcpSender) send(batch []byte) error {
+	if t.conn == nil {
+		conn, err := net.DialTimeout("tcp", t.address, t.timeout)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+
+	t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	if _, err := t.conn.Write(batch); err != nil {
+		t.close()
+		return err
+	}
+
+	return nil
+}
+
+func (t *tcpSender) close() {
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// the spill directories opened, which are shared by the shippers of
+// the same SpillDir, e.g. the old and new ones during Reload.
+var _spillDirs = struct {
+	sync.Mutex
+	dirs map[string]*spillDir
+}{dirs: map[string]*spillDir{}}
+
+// spillDir keeps the batches in files named by sequence, the oldest
+// ones are removed if the size exceeds limit. A segment is claimed by
+// one shipper at a time to replay.
+type spillDir struct {
+	dir   string
+	limit int64
+	refs  int
+
+	mu       sync.Mutex
+	segments []string
+	sizes    map[string]int64
+	counts   map[string]int
+	claimed  map[string]bool
+	size     int64
+	seq      uint64
+}
+
+// openSpillDir opens dir, or shares the one opened already, the
+// limit of the latest is applied. It is released by close.
+func openSpillDir(dir string, limit int64) (*spillDir, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	_spillDirs.Lock()
+	defer _spillDirs.Unlock()
+
+	if d, ok := _spillDirs.dirs[abs]; ok {
+		d.mu.Lock()
+		d.limit = limit
+		d.mu.Unlock()
+
+		d.refs++
+		return d, nil
+	}
+
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &spillDir{
+		dir:     abs,
+		limit:   limit,
+		refs:    1,
+		sizes:   map[string]int64{},
+		counts:  map[string]int{},
+		claimed: map[string]bool{},
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, _spillExt), 10, 64)
+		if entry.IsDir() || !strings.HasSuffix(name, _spillExt) || err != nil {
+			continue
+		}
+
+		path := filepath.Join(abs, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		d.segments = append(d.segments, path)
+		d.sizes[path] = int64(len(data))
+		d.counts[path] = bytes.Count(data, []byte{'\n'})
+		d.size += int64(len(data))
+		if seq > d.seq {
+			d.seq = seq
+		}
+	}
+	sort.Strings(d.segments)
+
+	_spillDirs.dirs[abs] = d
+	return d, nil
+}
+
+func (d *spillDir) close() {
+	_spillDirs.Lock()
+	defer _spillDirs.Unlock()
+
+	if d.refs--; d.refs == 0 {
+		delete(_spillDirs.dirs, d.dir)
+	}
+}
+
+func (d *spillDir) Empty() bool {
+	if d == nil {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.segments) == 0
+}
+
+func (d *spillDir) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// Append writes batch of count entries as the newest segment, and
+// returns the number of entries dropped for the limit.
+func (d *spillDir) Append(batch []byte, count int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	path := filepath.Join(d.dir, fmt.Sprintf("%020d%s", d.seq, _spillExt))
+	if err := os.WriteFile(path, batch, 0o644); err != nil {
+		return 0, err
+	}
+
+	d.segments = append(d.segments, path)
+	d.sizes[path] = int64(len(batch))
+	d.counts[path] = count
+	d.size += int64(len(batch))
+
+	dropped := 0
+	for d.limit > 0 && d.size > d.limit {
+		oldest := d.oldest()
+		if oldest == "" {
+			break
+		}
+
+		dropped += d.counts[oldest]
+		d.remove(oldest)
+	}
+
+	return dropped, nil
+}
+
+// oldest returns the oldest segment not claimed.
+func (d *spillDir) oldest() string {
+	for _, segment := range d.segments {
+		if !d.claimed[segment] {
+			return segment
+		}
+	}
+
+	return ""
+}
+
+// Claim returns the oldest segment not claimed with its data and
+// number of entries, ok is false if there is none. The segment is
+// claimed until Remove or Release.
+func (d *spillDir) Claim() (segment string, data []byte, count int, ok bool, err error) {
+	d.mu.Lock()
+	segment = d.oldest()
+	if segment == "" {
+		d.mu.Unlock()
+		return "", nil, 0, false, nil
+	}
+
+	d.claimed[segment] = true
+	count = d.counts[segment]
+	d.mu.Unlock()
+
+	data, err = os.ReadFile(segment)
+	return segment, data, count, true, err
+}
+
+func (d *spillDir) Release(segment string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.claimed, segment)
+}
+
+func (d *spillDir) Remove(segment string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(segment)
+}
+
+func (d *spillDir) remove(segment string) {
+	for i, s := range d.segments {
+		if s == segment {
+			d.segments = append(d.segments[:i], d.segments[i+1:]...)
+			break
+		}
+	}
+
+	os.Remove(segment)
+	d.size -= d.sizes[segment]
+	delete(d.sizes, segment)
+	delete(d.counts, segment)
+	delete(d.claimed, segment)
+}
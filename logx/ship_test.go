@@ -0,0 +1,192 @@
+package logx
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type collector struct {
+	down  int32
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&c.down) == 1 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	c.mu.Lock()
+	for scanner.Scan() {
+		c.lines = append(c.lines, scanner.Text())
+	}
+	c.mu.Unlock()
+}
+
+func (c *collector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected condition before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestShipper(t *testing.T, url, limit string) *Shipper {
+	s, err := NewShipper(&ShipConfig{
+		URL:           url,
+		BatchSize:     10,
+		BatchInterval: "20ms",
+		MaxRetries:    1,
+		MinBackoff:    "10ms",
+		MaxBackoff:    "50ms",
+		SpillDir:      t.TempDir(),
+		SpillLimit:    limit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestShipper(t *testing.T) {
+	c := &collector{down: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	s := newTestShipper(t, server.URL, "1MiB")
+	for i := 0; i < 25; i++ {
+		fmt.Fprintf(s, "entry %d\n", i)
+	}
+	s.Sync()
+
+	if stats := s.Stats(); stats.Spilled != 25 || stats.Sent != 0 || stats.SpillSize == 0 {
+		t.Fatalf("Expected 25 entries spilled, got: %+v", stats)
+	}
+
+	atomic.StoreInt32(&c.down, 0)
+	waitFor(t, func() bool { return s.Stats().Replayed == 25 })
+
+	fmt.Fprintf(s, "entry %d\n", 25)
+	s.Sync()
+	if stats := s.Stats(); stats.Sent != 1 || stats.SpillSize != 0 {
+		t.Fatalf("Expected 1 entry sent, got: %+v", stats)
+	}
+
+	lines := c.received()
+	if len(lines) != 26 {
+		t.Fatalf("Expected 26 entries, got: %v", lines)
+	}
+
+	for i, line := range lines {
+		if line != fmt.Sprintf("entry %d", i) {
+			t.Fatalf("Expected entries in order, got: %v", lines)
+		}
+	}
+}
+
+func TestShipperSpillLimit(t *testing.T) {
+	c := &collector{down: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	s := newTestShipper(t, server.URL, "100B")
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 5; j++ {
+			fmt.Fprintf(s, "entry %d\n", i*5+j)
+		}
+		s.Sync()
+	}
+
+	stats := s.Stats()
+	if stats.Dropped == 0 || stats.SpillSize > 100 {
+		t.Fatalf("Expected spill limited to 100B, got: %+v", stats)
+	}
+
+	atomic.StoreInt32(&c.down, 0)
+	waitFor(t, func() bool { return s.Stats().SpillSize == 0 })
+
+	lines := c.received()
+	if len(lines) == 0 || lines[len(lines)-1] != "entry 49" {
+		t.Fatalf("Expected the newest entries replayed, got: %v", lines)
+	}
+}
+
+func TestShipperSharedSpillDir(t *testing.T) {
+	c := &collector{down: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	config := &ShipConfig{URL: server.URL, MaxRetries: 1, MinBackoff: "10ms", MaxBackoff: "50ms", SpillDir: t.TempDir()}
+	old, err := NewShipper(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the shipper rebuilt by reload before the old one is closed
+	s, err := NewShipper(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(old, "old %d\n", i)
+		fmt.Fprintf(s, "new %d\n", i)
+		old.Sync()
+		s.Sync()
+	}
+	old.Close()
+
+	atomic.StoreInt32(&c.down, 0)
+	waitFor(t, func() bool { return s.Stats().SpillSize == 0 })
+
+	lines := c.received()
+	seen := map[string]bool{}
+	for _, line := range lines {
+		seen[line] = true
+	}
+
+	if len(lines) != 20 || len(seen) != 20 {
+		t.Fatalf("Expected 20 entries once, got: %v", lines)
+	}
+}
+
+func TestShipperLostSegment(t *testing.T) {
+	c := &collector{down: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	s := newTestShipper(t, server.URL, "")
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(s, "entry %d\n", i)
+	}
+	s.Sync()
+
+	s.spill.mu.Lock()
+	for _, segment := range s.spill.segments {
+		os.Remove(segment)
+	}
+	s.spill.mu.Unlock()
+
+	atomic.StoreInt32(&c.down, 0)
+	waitFor(t, func() bool { return s.Stats().Dropped == 5 })
+}
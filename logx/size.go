@@ -0,0 +1,48 @@
+package logx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var _sizeUnits = map[string]float64{
+	"b":   1,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// parseSize parses size string with unit in bytes, e.g. "50MiB",
+// "1.5 GiB", a size without unit is in bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	number, unit := s, "b"
+	if i >= 0 {
+		number, unit = s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	}
+
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	scale, ok := _sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit '%s'", unit)
+	}
+
+	bytes := size * scale
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	return int64(bytes), nil
+}
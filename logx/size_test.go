@@ -0,0 +1,29 @@
+package logx
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected int64
+	}{
+		{"512", 512},
+		{"1B", 1},
+		{"4KiB", 4096},
+		{"1.5 MiB", 3 << 19},
+		{"2gib", 2 << 30},
+	}
+
+	for _, test := range tests {
+		size, err := parseSize(test.s)
+		if err != nil || size != test.expected {
+			t.Fatalf("Expected %d of %s, got: %d, %v", test.expected, test.s, size, err)
+		}
+	}
+
+	for _, s := range []string{"", "MiB", "10MB", "-1KiB"} {
+		if _, err := parseSize(s); err == nil {
+			t.Fatalf("Expected invalid size of %q", s)
+		}
+	}
+}
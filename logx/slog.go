@@ -0,0 +1,312 @@
+//go:build go1.21
+
+package logx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func makeZapLevelFromSlog(lvl slog.Level) zapcore.Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case lvl < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case lvl < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+func makeSlogLevel(lvl zapcore.Level) slog.Level {
+	switch {
+	case lvl < zapcore.InfoLevel:
+		return slog.LevelDebug
+	case lvl < zapcore.WarnLevel:
+		return slog.LevelInfo
+	case lvl < zapcore.ErrorLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// slogHandler is a slog.Handler which writes records to a zap core,
+// groups are mapped to zap namespaces.
+type slogHandler struct {
+	core       zapcore.Core
+	addCaller  bool
+	stackLevel zapcore.LevelEnabler
+}
+
+// SlogHandler returns a slog.Handler sharing the level, encoder,
+// outputs, caller and stacktrace options of the logger.
+func (z *zapLogger) SlogHandler() slog.Handler {
+	return &slogHandler{core: z.Logger().Core(), addCaller: z.addCaller, stackLevel: z.stackLevel}
+}
+
+// SlogHandler returns a slog.Handler backed by the global logger.
+func SlogHandler() slog.Handler {
+	return global().SlogHandler()
+}
+
+// NewSlogHandler builds a logger with config, and returns
+// a slog.Handler backed by it.
+func NewSlogHandler(config *Config) (slog.Handler, error) {
+	lg, err := InitZapLogger(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return lg.SlogHandler(), nil
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.core.Enabled(makeZapLevelFromSlog(lvl))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:   makeZapLevelFromSlog(r.Level),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+
+	var frame runtime.Frame
+	if r.PC != 0 {
+		frame, _ = runtime.CallersFrames([]uintptr{r.PC}).Next()
+	}
+
+	if h.addCaller && r.PC != 0 {
+		ent.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ent.Caller.Function = frame.Function
+	}
+
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	if h.stackLevel != nil && h.stackLevel.Enabled(ent.Level) {
+		ce.Stack = stacktraceFrom(frame)
+	}
+
+	fields := make([]zapcore.Field, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, attr)
+		return true
+	})
+
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := appendSlogFields(make([]zapcore.Field, 0, len(attrs)), attrs)
+	return &slogHandler{core: h.core.With(fields), addCaller: h.addCaller, stackLevel: h.stackLevel}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{
+		core:       h.core.With([]zapcore.Field{zap.Namespace(name)}),
+		addCaller:  h.addCaller,
+		stackLevel: h.stackLevel,
+	}
+}
+
+// stacktraceFrom formats the stack like zap from the caller of slog,
+// the frames of slog and handlers are skipped. The whole stack is
+// formatted if caller is not found.
+func stacktraceFrom(caller runtime.Frame) string {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	frames := []runtime.Frame{}
+	callers := runtime.CallersFrames(pcs)
+	for {
+		frame, more := callers.Next()
+		if frame.Function == caller.Function && frame.File == caller.File && frame.Line == caller.Line {
+			frames = frames[:0]
+		}
+
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+
+	var b strings.Builder
+	for i, frame := range frames {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+
+	return b.String()
+}
+
+func appendSlogAttr(fields []zapcore.Field, attr slog.Attr) []zapcore.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return fields
+		}
+
+		// a group without key is inlined
+		if attr.Key == "" {
+			for _, a := range group {
+				fields = appendSlogAttr(fields, a)
+			}
+			return fields
+		}
+
+		return append(fields, zap.Object(attr.Key, slogGroup(group)))
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, attr.Value.Time()))
+	default:
+		if err, ok := attr.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(attr.Key, err))
+		}
+
+		return append(fields, zap.Any(attr.Key, attr.Value.Any()))
+	}
+}
+
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range appendSlogFields(nil, g) {
+		field.AddTo(enc)
+	}
+
+	return nil
+}
+
+func appendSlogFields(fields []zapcore.Field, attrs []slog.Attr) []zapcore.Field {
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+
+	return fields
+}
+
+// slogCore is a zapcore.Core which routes entries to a slog.Handler.
+type slogCore struct {
+	handler slog.Handler
+	fields  []zapcore.Field
+}
+
+// NewSlogCore creates a zapcore.Core writing to handler.
+func NewSlogCore(handler slog.Handler) zapcore.Core {
+	return &slogCore{handler: handler}
+}
+
+// WithSlogHandler writes the entries to handler instead of the
+// outputs, the level, sampling and other options still apply and
+// are reloaded.
+func WithSlogHandler(handler slog.Handler) configurer {
+	return func(c *Config) {
+		c.external = NewSlogCore(handler)
+	}
+}
+
+func (c *slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), makeSlogLevel(lvl))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &slogCore{handler: c.handler, fields: merged}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, makeSlogLevel(ent.Level), ent.Message, ent.Caller.PC)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	r.AddAttrs(makeSlogAttrs(all)...)
+
+	if ent.Stack != "" {
+		r.AddAttrs(slog.String("stack", ent.Stack))
+	}
+
+	return c.handler.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// makeSlogAttrs converts zap fields to slog attributes, the fields
+// following a namespace are put into a group.
+func makeSlogAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for i, field := range fields {
+		if field.Type == zapcore.NamespaceType {
+			group := makeSlogAttrs(fields[i+1:])
+			return append(attrs, slog.Attr{Key: field.Key, Value: slog.GroupValue(group...)})
+		}
+
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+
+		// a field may add more than one key, e.g. error and errorVerbose
+		keys := make([]string, 0, len(enc.Fields))
+		for key := range enc.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			attrs = append(attrs, slog.Any(key, enc.Fields[key]))
+		}
+	}
+
+	return attrs
+}
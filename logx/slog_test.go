@@ -0,0 +1,122 @@
+//go:build go1.21
+
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSlogHandler(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := InitZapLogger(&Config{
+		Lvl:           LvlInfo,
+		Format:        FormatJson,
+		File:          NewFileConfig(dir, "slog.log", 1, 1, 1, false),
+		DisableCaller: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(lg.SlogHandler())
+	logger.Debug("ignored")
+	logger.WithGroup("req").Info("hello", "method", "GET", slog.Int("status", 200))
+	lg.Sync()
+
+	data, err := os.ReadFile(filepath.Join(dir, "slog.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("Expected one entry, got: %s", data)
+	}
+
+	req, _ := entry["req"].(map[string]interface{})
+	if entry["message"] != "hello" || req["method"] != "GET" || req["status"] != float64(200) {
+		t.Fatalf("Unexpected entry: %s", data)
+	}
+}
+
+func TestSlogCore(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	logger := zap.New(NewSlogCore(handler)).With(zap.String("app", "pkg"))
+	logger.Debug("ignored")
+	logger.Info("hello", zap.Namespace("req"), zap.Error(errors.New("boom")))
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+		t.Fatalf("Expected one entry, got: %s", buf.String())
+	}
+
+	req, _ := entry["req"].(map[string]interface{})
+	if entry["msg"] != "hello" || entry["app"] != "pkg" || req["error"] != "boom" {
+		t.Fatalf("Unexpected entry: %s", buf.String())
+	}
+}
+
+func TestWithSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	lg, err := NewZapLogger(WithSlogHandler(handler), WithLevel(LvlInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	logger := lg.Logger()
+	logger.Debug("ignored")
+	logger.Info("started")
+
+	// the config loaded from file has no handler
+	if err := lg.Reload(&Config{Lvl: LvlWarn, Format: FormatJson}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("ignored after reload")
+	logger.Warn("slow")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"started"`) || !strings.Contains(lines[1], `"msg":"slow"`) {
+		t.Fatalf("Expected started and slow, got: %s", buf.String())
+	}
+}
+
+func TestSlogHandlerStacktrace(t *testing.T) {
+	dir := t.TempDir()
+	lg, err := InitZapLogger(&Config{Lvl: LvlInfo, Format: FormatJson, File: NewFileConfig(dir, "slog.log", 1, 1, 1, false)}, zap.AddCaller())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	slog.New(lg.SlogHandler()).Error("failed")
+	lg.Sync()
+
+	data, err := os.ReadFile(filepath.Join(dir, "slog.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("Expected one entry, got: %s", data)
+	}
+
+	stack, _ := entry["stack"].(string)
+	if !strings.HasPrefix(stack, "github.com/chao77977/pkg/logx.TestSlogHandlerStacktrace\n") {
+		t.Fatalf("Expected stacktrace from the test, got: %s", data)
+	}
+}
@@ -0,0 +1,286 @@
+package logx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var _syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3,
+	"auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// the local sockets of syslog daemon, see log/syslog.
+var _syslogLocalAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig represents a syslog output in toml/json, entries are sent
+// in RFC 5424 over Network "unix", "unixgram", "udp" or "tcp" to Address,
+// or to the local syslog daemon if Network is empty. Facility is like
+// "user" (default) or "local0", and Tag is the name of program by default,
+// which has at most 48 printable ASCII characters without spaces.
+type SyslogConfig struct {
+	Network  string `toml:"network" json:"network"`
+	Address  string `toml:"address" json:"address"`
+	Facility string `toml:"facility" json:"facility"`
+	Tag      string `toml:"tag" json:"tag"`
+}
+
+func (s *SyslogConfig) validate(key string) []error {
+	errs := []error{}
+	switch s.Network {
+	case "":
+	case "unix", "unixgram", "udp", "tcp":
+		if s.Address == "" {
+			errs = append(errs, &ConfigError{Key: key + ".address", Err: errors.New("no address is configured")})
+		}
+	default:
+		errs = append(errs, &ConfigError{Key: key + ".network", Err: fmt.Errorf("invalid network '%s'", s.Network)})
+	}
+
+	if s.Tag != "" && syslogAppName(s.Tag) != s.Tag {
+		errs = append(errs, &ConfigError{Key: key + ".tag", Err: fmt.Errorf("invalid tag '%s'", s.Tag)})
+	}
+
+	if _, ok := _syslogFacilities[s.Facility]; s.Facility != "" && !ok {
+		errs = append(errs, &ConfigError{Key: key + ".facility", Err: fmt.Errorf("invalid facility '%s'", s.Facility)})
+	}
+
+	return errs
+}
+
+// the length of APP-NAME in RFC 5424
+const _syslogAppNameLen = 48
+
+// syslogAppName replaces the characters not in PRINTUSASCII of name
+// with underscores, and truncates it to the length of APP-NAME.
+func syslogAppName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+
+	if len(b) > _syslogAppNameLen {
+		b = b[:_syslogAppNameLen]
+	}
+
+	return string(b)
+}
+
+// syslogSeverity maps level to the severity of RFC 5424, which is
+// also the priority of journald. Fatal is alert, since the program
+// exits.
+func syslogSeverity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.FatalLevel:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// syslogConn sends messages and reconnects once if it fails, each
+// Write is a message.
+type syslogConn struct {
+	network string
+	address string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func (c *syslogConn) dial() error {
+	if c.network != "" {
+		conn, err := net.Dial(c.network, c.address)
+		if err != nil {
+			return err
+		}
+
+		c.conn = conn
+		return nil
+	}
+
+	for _, addr := range _syslogLocalAddrs {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, addr); err == nil {
+				c.conn, c.network = conn, network
+				return nil
+			}
+		}
+	}
+
+	return errors.New("no local syslog daemon")
+}
+
+func (c *syslogConn) Write(msg []byte) (int, error) {
+	if err := c.write(msg); err != nil {
+		return 0, err
+	}
+
+	return len(msg), nil
+}
+
+func (c *syslogConn) Sync() error {
+	return nil
+}
+
+// write frames msg by octet counting of RFC 6587 over streams.
+func (c *syslogConn) write(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errOutputClosed
+	}
+
+	if c.conn != nil {
+		if err := c.send(msg); err == nil {
+			return nil
+		}
+
+		c.conn.Close()
+		c.conn = nil
+	}
+
+	if err := c.dial(); err != nil {
+		return err
+	}
+
+	return c.send(msg)
+}
+
+func (c *syslogConn) send(msg []byte) error {
+	if c.network == "tcp" || c.network == "unix" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+func (c *syslogConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func newSyslogConn(config *SyslogConfig) (*syslogConn, error) {
+	conn := &syslogConn{network: config.Network, address: config.Address}
+	if err := conn.dial(); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// syslogCore writes entries encoded by enc as the messages of syslog
+// to ws, which is the syslogConn or the async writer of it.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	ws       zapcore.WriteSyncer
+	facility int
+	hostname string
+	tag      string
+	pid      string
+}
+
+func newSyslogCore(config *SyslogConfig, enc zapcore.Encoder, ws zapcore.WriteSyncer, enab zapcore.LevelEnabler) *syslogCore {
+	facility, ok := _syslogFacilities[config.Facility]
+	if !ok {
+		facility = _syslogFacilities["user"]
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	tag := config.Tag
+	if tag == "" {
+		tag = syslogAppName(programName())
+	}
+
+	return &syslogCore{
+		LevelEnabler: enab,
+		enc:          enc,
+		ws:           ws,
+		facility:     facility,
+		hostname:     hostname,
+		tag:          tag,
+		pid:          strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.enc)
+	}
+
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write sends "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s - - ",
+		c.facility*8+syslogSeverity(ent.Level),
+		ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		c.hostname, c.tag, c.pid)
+
+	data := append([]byte(msg), bytes.TrimRight(buf.Bytes(), "\n")...)
+	_, err = c.ws.Write(data)
+	return err
+}
+
+func (c *syslogCore) Sync() error {
+	return c.ws.Sync()
+}
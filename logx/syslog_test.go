@@ -0,0 +1,173 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func listenUnixgram(t *testing.T) (string, *net.UnixConn) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return path, conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf[:n]
+}
+
+func TestSyslogOutput(t *testing.T) {
+	path, conn := listenUnixgram(t)
+	lg, err := NewZapLogger(WithOutput(&OutputConfig{
+		Type:   OutputSyslog,
+		Syslog: &SyslogConfig{Network: "unixgram", Address: path, Facility: "local0", Tag: "app"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	lg.Logger().Warn("disk is slow", zap.String("disk", "sda"))
+	msg := string(readDatagram(t, conn))
+
+	// local0 * 8 + warning
+	if !strings.HasPrefix(msg, "<132>1 ") || !strings.Contains(msg, " app ") ||
+		!strings.Contains(msg, `"message":"disk is slow"`) || !strings.Contains(msg, `"disk":"sda"`) {
+		t.Fatalf("Expected syslog message, got: %s", msg)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	lg, err = NewZapLogger(WithOutput(&OutputConfig{
+		Type:   OutputSyslog,
+		Syslog: &SyslogConfig{Network: "tcp", Address: ln.Addr().String()},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	lg.Logger().Error("failed")
+	line, err := bufio.NewReader(server).ReadString('>')
+	if err != nil || !strings.HasSuffix(line, " <11>") {
+		t.Fatalf("Expected octet counting frame of user error, got: %s, %v", line, err)
+	}
+}
+
+func TestJournaldOutput(t *testing.T) {
+	path, conn := listenUnixgram(t)
+	lg, err := InitZapLogger(newConfig(WithOutput(&OutputConfig{
+		Type:     OutputJournald,
+		Journald: &JournaldConfig{Socket: path, Identifier: "app"},
+	})), zap.AddCaller())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	lg.Logger().Named("storage").Error("write failed",
+		zap.Int("user_id", 42),
+		zap.String("detail", "line 1\nline 2"),
+		zap.String("_hidden", "x"),
+		zap.String("message", "shadowed"),
+		zap.Int("priority", 7),
+	)
+	payload := readDatagram(t, conn)
+
+	for _, field := range []string{
+		"MESSAGE=write failed\n", "PRIORITY=3\n", "SYSLOG_IDENTIFIER=app\n",
+		"LOGGER=storage\n", "USER_ID=42\n", "HIDDEN=x\n", "CODE_FILE=",
+		"F_MESSAGE=shadowed\n", "F_PRIORITY=7\n",
+	} {
+		if !bytes.Contains(payload, []byte(field)) {
+			t.Fatalf("Expected %q, got: %q", field, payload)
+		}
+	}
+
+	value := "line 1\nline 2"
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	if !bytes.Contains(payload, []byte("DETAIL\n"+string(size)+value+"\n")) {
+		t.Fatalf("Expected binary DETAIL field, got: %q", payload)
+	}
+}
+
+func TestSyslogTag(t *testing.T) {
+	for _, tag := range []string{"my app", "app\x00", strings.Repeat("a", 49)} {
+		if errs := (&SyslogConfig{Tag: tag}).validate("syslog"); len(errs) != 1 {
+			t.Fatalf("Expected invalid tag %q, got: %v", tag, errs)
+		}
+	}
+
+	if errs := (&SyslogConfig{Tag: "app-1.0"}).validate("syslog"); len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	if name := syslogAppName("my app\t"); name != "my_app_" {
+		t.Fatalf("Expected app name: my_app_, got: %s", name)
+	}
+}
+
+func TestSyslogAsync(t *testing.T) {
+	path, conn := listenUnixgram(t)
+	lg, err := NewZapLogger(WithAsync("64KiB", time.Second, 16, DropPolicyBlock), WithOutput(&OutputConfig{
+		Name:   "syslog",
+		Type:   OutputSyslog,
+		Syslog: &SyslogConfig{Network: "unixgram", Address: path, Tag: "app"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lg.Close()
+
+	lg.Logger().Info("first")
+	lg.Logger().Info("second")
+	if err := lg.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// an entry per datagram rather than the buffer of entries
+	for _, expected := range []string{"first", "second"} {
+		msg := string(readDatagram(t, conn))
+		if !strings.HasPrefix(msg, "<14>1 ") || !strings.Contains(msg, `"message":"`+expected+`"`) || strings.Count(msg, "<14>1 ") != 1 {
+			t.Fatalf("Expected syslog message of %s, got: %s", expected, msg)
+		}
+	}
+
+	if stats := lg.AsyncStats(); len(stats) != 1 || stats[0].Name != "syslog" {
+		t.Fatalf("Expected async stats of syslog, got: %v", stats)
+	}
+
+	if severity := syslogSeverity(zap.FatalLevel); severity != 1 {
+		t.Fatalf("Expected alert severity of fatal, got: %d", severity)
+	}
+}
@@ -0,0 +1,166 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chao77977/pkg/errorx"
+	"go.uber.org/zap/zapcore"
+)
+
+// hasOutput returns if any output is configured.
+func (c *Config) hasOutput() bool {
+	hasFile := c.File != nil && len(c.File.Name) > 0
+	return hasFile || c.OsStdout || len(c.Outputs) > 0 || c.external != nil
+}
+
+// Validate checks the configuration, and returns errorx.Errors of
+// ConfigError so that all the problems are reported at once.
+func (c *Config) Validate() error {
+	errs := errorx.NewErrors(0)
+
+	if makeZapLogLevel(c.Lvl) == zapcore.InvalidLevel {
+		errs.Add(&ConfigError{Key: "level", Err: fmt.Errorf("invalid log level '%d'", c.Lvl)})
+	}
+
+	if !isValidFormat(c.Format) {
+		errs.Add(&ConfigError{Key: "format", Err: fmt.Errorf("invalid log format '%s'", c.Format)})
+	}
+
+	if !isValidColor(c.Color) {
+		errs.Add(&ConfigError{Key: "color", Err: fmt.Errorf("invalid color '%s'", c.Color)})
+	}
+
+	for name, lvl := range c.Modules {
+		if name == "" {
+			errs.Add(&ConfigError{Key: "modules", Err: errors.New("module name cannot be empty")})
+		}
+
+		if makeZapLogLevel(lvl) == zapcore.InvalidLevel {
+			errs.Add(&ConfigError{Key: "modules." + name, Err: fmt.Errorf("invalid log level '%d'", lvl)})
+		}
+	}
+
+	hasFile := c.File != nil && len(c.File.Name) > 0
+	if hasFile {
+		errs.Combine(c.File.validate("file")...)
+	}
+
+	names := map[string]bool{}
+	for i, output := range c.Outputs {
+		key := fmt.Sprintf("outputs[%d]", i)
+		if output == nil {
+			errs.Add(&ConfigError{Key: key, Err: errors.New("empty output")})
+			continue
+		}
+
+		if output.Name != "" {
+			if names[output.Name] {
+				errs.Add(&ConfigError{Key: key + ".name", Err: fmt.Errorf("duplicated output '%s'", output.Name)})
+			}
+			names[output.Name] = true
+		}
+
+		errs.Combine(output.validate(key)...)
+	}
+
+	if c.Async != nil {
+		errs.Combine(c.Async.validate("async")...)
+	}
+
+	if c.Sampling != nil {
+		errs.Combine(c.Sampling.validate("sampling")...)
+	}
+
+	if c.Dedup != nil {
+		errs.Combine(c.Dedup.validate("dedup")...)
+	}
+
+	if c.Redact != nil {
+		errs.Combine(c.Redact.validate("redact")...)
+	}
+
+	if !c.hasOutput() {
+		errs.Add(&ConfigError{Key: "os-stdout", Err: errors.New("no output is configured")})
+	}
+
+	if len(errs.WrappedErrors()) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (f *FileConfig) validate(key string) []error {
+	errs := []error{}
+	if f.MaxSize < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".maxsize", Err: fmt.Errorf("negative size '%d'", f.MaxSize)})
+	}
+
+	if f.MaxAge < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".maxage", Err: fmt.Errorf("negative age '%d'", f.MaxAge)})
+	}
+
+	if f.MaxBackups < 0 {
+		errs = append(errs, &ConfigError{Key: key + ".maxbackups", Err: fmt.Errorf("negative backups '%d'", f.MaxBackups)})
+	}
+
+	if f.Rotation != "" {
+		if _, err := parseRotation(f.Rotation); err != nil {
+			errs = append(errs, &ConfigError{Key: key + ".rotation", Err: err})
+		}
+	}
+
+	if f.MaxTotalSize != "" {
+		if _, err := parseSize(f.MaxTotalSize); err != nil {
+			errs = append(errs, &ConfigError{Key: key + ".max-total-size", Err: err})
+		}
+	}
+
+	if stat, err := os.Stat(filepath.Join(f.Path, f.Name)); err == nil && stat.IsDir() {
+		errs = append(errs, &ConfigError{Key: key + ".name", Err: errors.New("log file cannot be directory")})
+	}
+
+	if err := checkWritableDir(f.Path); err != nil {
+		errs = append(errs, &ConfigError{Key: key + ".path", Err: err})
+	}
+
+	return errs
+}
+
+// checkWritableDir checks if dir is writable, or can be created
+// when it does not exist.
+func checkWritableDir(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	// find the nearest existing directory
+	for {
+		stat, err := os.Stat(dir)
+		if err == nil {
+			if !stat.IsDir() {
+				return fmt.Errorf("'%s' is not a directory", dir)
+			}
+			break
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	if !canWrite(dir) {
+		return fmt.Errorf("directory '%s' is not writable", dir)
+	}
+
+	return nil
+}
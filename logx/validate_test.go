@@ -0,0 +1,61 @@
+package logx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chao77977/pkg/errorx"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Lvl:    Level(42),
+		Format: "xml",
+		File:   NewFileConfig(filepath.Join(file, "logs"), "app.log", -1, 0, 0, false),
+	}
+
+	err := config.Validate()
+	var errs *errorx.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected errorx.Errors, got: %v", err)
+	}
+
+	keys := map[string]bool{}
+	for _, e := range errs.WrappedErrors() {
+		configErr := &ConfigError{}
+		if errors.As(e, &configErr) {
+			keys[configErr.Key] = true
+		}
+	}
+
+	for _, key := range []string{"level", "format", "file.maxsize", "file.path"} {
+		if !keys[key] {
+			t.Fatalf("Expected error of key %s, got: %v", key, err)
+		}
+	}
+
+	if _, err := NewZapLogger(WithLevel(LvlInfo)); err != nil {
+		t.Fatal(err)
+	}
+
+	// stdout is not added along with the file
+	if newConfig(WithFile(t.TempDir(), "app.log", 10, 0, 0, false)).OsStdout {
+		t.Fatal("Unexpected stdout along with file")
+	}
+
+	if !newConfig(WithConsole(), WithFile(t.TempDir(), "app.log", 10, 0, 0, false)).OsStdout {
+		t.Fatal("Expected stdout with console")
+	}
+
+	if _, err := NewZapLogger(WithoutConsole()); err == nil {
+		t.Fatal("Unexpected logger without output")
+	}
+}
@@ -2,6 +2,8 @@ package logx
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"sync"
 
 	"go.uber.org/zap"
@@ -29,34 +31,80 @@ func makeZapLogLevel(lvl Level) zapcore.Level {
 	}
 }
 
+func makeLevel(lvl zapcore.Level) Level {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return LvlDebug
+	case zapcore.InfoLevel:
+		return LvlInfo
+	case zapcore.WarnLevel:
+		return LvlWarn
+	case zapcore.ErrorLevel:
+		return LvlError
+	case zapcore.DPanicLevel:
+		return LvlDPanic
+	case zapcore.PanicLevel:
+		return LvlPanic
+	default:
+		return LvlFatal
+	}
+}
+
 type zapLogger struct {
 	leveledLogger  sync.Map
 	leveledSLogger sync.Map
-	level          Level
+
+	// level is shared by logger and sLogger, so changing it applies
+	// to the loggers already handed out.
+	level   zap.AtomicLevel
+	logger  *zap.Logger
+	sLogger *zap.SugaredLogger
+
+	// globalLogger skips the caller of the package level functions.
+	globalLogger *zap.Logger
+
+	base         *zap.Logger
+	modules      *moduleLevels
+	namedLoggers sync.Map
+
+	mu      sync.Mutex
+	core    *reloadableCore
+	closers []io.Closer
+	metrics Metrics
+	// external is kept by Reload like metrics, see WithSlogHandler.
+	external zapcore.Core
+
+	addCaller  bool
+	stackLevel zapcore.LevelEnabler
 }
 
+// InitZapLogger initializes the logger of config, the options of config
+// (caller and stacktrace) are applied after opts if opts are given. The
+// fields of Process are always attached.
 func InitZapLogger(config *Config, opts ...zap.Option) (*zapLogger, error) {
 	if config == nil {
 		return nil, errors.New("no configuration to initialize zap logger")
 	}
 
-	syncers, err := config.BuildZapWriteSyncer()
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
-	//level debug
-	core := zapcore.NewCore(
-		config.BuildZapEncoder(),
-		zap.CombineWriteSyncers(syncers...),
-		zap.NewAtomicLevelAt(zap.DebugLevel),
-	)
+	core, closers, err := config.buildZapCore()
+	if err != nil {
+		return nil, err
+	}
 
-	if opts != nil {
+	withOptions := opts != nil
+	if withOptions {
 		opts = append(opts, config.BuildZapOptions()...)
+	} else if config.Process != nil {
+		opts = append(opts, zap.Fields(config.Process.fields()...))
 	}
 
-	debugLogger := zap.New(core, opts...)
+	// level debug, the core can be swapped by Reload
+	reloadable := newReloadableCore(core)
+	debugLogger := zap.New(reloadable, opts...)
 
 	levels := []Level{
 		LvlDebug,
@@ -68,7 +116,26 @@ func InitZapLogger(config *Config, opts ...zap.Option) (*zapLogger, error) {
 		LvlFatal,
 	}
 
-	lg := zapLogger{level: config.Lvl}
+	lg := zapLogger{
+		level:    zap.NewAtomicLevelAt(makeZapLogLevel(config.Lvl)),
+		base:     debugLogger,
+		core:     reloadable,
+		closers:  closers,
+		metrics:  config.Metrics,
+		external: config.external,
+	}
+	// the slog handler follows the options of logger
+	if withOptions {
+		lg.addCaller, lg.stackLevel = !config.DisableCaller, config.stackLevel()
+	}
+	lg.modules = newModuleLevels(lg.level)
+	for name, lvl := range config.Modules {
+		lg.modules.Set(name, makeZapLogLevel(lvl))
+	}
+
+	lg.logger = debugLogger.WithOptions(zap.IncreaseLevel(lg.level))
+	lg.sLogger = lg.logger.Sugar()
+	lg.globalLogger = lg.logger.WithOptions(zap.AddCallerSkip(1))
 	for _, level := range levels {
 		promotedLogger := debugLogger.WithOptions(zap.IncreaseLevel(makeZapLogLevel(level)))
 		lg.leveledLogger.Store(level, promotedLogger)
@@ -79,8 +146,7 @@ func InitZapLogger(config *Config, opts ...zap.Option) (*zapLogger, error) {
 }
 
 func (z *zapLogger) Logger() *zap.Logger {
-	lg, _ := z.leveledLogger.Load(z.level)
-	return lg.(*zap.Logger)
+	return z.logger
 }
 
 func (z *zapLogger) DebugLogger() *zap.Logger {
@@ -109,8 +175,7 @@ func (z *zapLogger) FatalLogger() *zap.Logger {
 }
 
 func (z *zapLogger) SLogger() *zap.SugaredLogger {
-	lg, _ := z.leveledSLogger.Load(z.level)
-	return lg.(*zap.SugaredLogger)
+	return z.sLogger
 }
 
 func (z *zapLogger) DebugSLogger() *zap.SugaredLogger {
@@ -139,7 +204,18 @@ func (z *zapLogger) FatalSLogger() *zap.SugaredLogger {
 }
 
 func (z *zapLogger) GetLevel() Level {
-	return z.level
+	return makeLevel(z.level.Level())
+}
+
+// SetLevel changes the level of Logger() and SLogger() at runtime.
+func (z *zapLogger) SetLevel(lvl Level) error {
+	zapLvl := makeZapLogLevel(lvl)
+	if zapLvl == zapcore.InvalidLevel {
+		return fmt.Errorf("invalid log level '%d'", lvl)
+	}
+
+	z.level.SetLevel(zapLvl)
+	return nil
 }
 
 func (z *zapLogger) Sync() error {
@@ -171,17 +247,78 @@ func (z *zapLogger) Sync() error {
 	return retErr
 }
 
-func NewZapLogger(configs ...configurer) (*zapLogger, error) {
-	initConfig := &Config{}
-	if configs == nil {
-		initConfig = NewDefaultConfig()
-	} else {
-		for _, config := range configs {
-			config(initConfig)
+// AsyncStats returns the queues of the outputs written in background,
+// see Config.Async.
+func (z *zapLogger) AsyncStats() []AsyncStats {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	stats := []AsyncStats{}
+	for _, closer := range z.closers {
+		if async, ok := closer.(*AsyncWriteSyncer); ok {
+			stats = append(stats, async.Stats())
 		}
 	}
 
-	return InitZapLogger(initConfig)
+	return stats
+}
+
+// ShipStats returns the delivery of the network outputs.
+func (z *zapLogger) ShipStats() []ShipStats {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	stats := []ShipStats{}
+	for _, closer := range z.closers {
+		if async, ok := closer.(*AsyncWriteSyncer); ok {
+			closer = async.closer
+		}
+
+		if s, ok := closer.(*Shipper); ok {
+			stats = append(stats, s.Stats())
+		}
+	}
+
+	return stats
+}
+
+// Close flushes the logger and closes its outputs, the logger
+// should not be used after Close.
+func (z *zapLogger) Close() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.core.Sync()
+
+	var retErr error
+	for _, closer := range z.closers {
+		if err := closer.Close(); err != nil {
+			retErr = err
+		}
+	}
+	z.closers = nil
+
+	return retErr
+}
+
+func NewZapLogger(configs ...configurer) (*zapLogger, error) {
+	return InitZapLogger(newConfig(configs...))
+}
+
+// newConfig applies configs to the default level and format, stdout
+// is written only if configs configure no other output.
+func newConfig(configs ...configurer) *Config {
+	initConfig := NewDefaultConfig()
+	initConfig.OsStdout = false
+	for _, config := range configs {
+		config(initConfig)
+	}
+
+	if !initConfig.consoleSet {
+		initConfig.OsStdout = !initConfig.hasOutput()
+	}
+
+	return initConfig
 }
 
 func NewLogger(configs ...configurer) (*zap.Logger, error) {
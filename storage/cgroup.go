@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	_cgroupV2MemoryLimit = "memory.max"
+	_cgroupV1Memory      = "memory"
+	_cgroupV1MemoryLimit = "memory.limit_in_bytes"
+
+	// cgroup v1 reports a page aligned max int64 when there is no limit
+	_cgroupV1Unlimited = 1 << 62
+)
+
+var (
+	_cgroupMu   sync.RWMutex
+	_cgroupRoot = "/sys/fs/cgroup"
+
+	// the cgroups of process, replaced by fixtures in tests
+	_procSelfCgroup = "/proc/self/cgroup"
+)
+
+// SetCgroupRoot changes the directory where cgroup memory limits are
+// read from, it is mostly useful for testing with fixtures.
+func SetCgroupRoot(root string) {
+	_cgroupMu.Lock()
+	defer _cgroupMu.Unlock()
+
+	_cgroupRoot = root
+}
+
+func cgroupRoot() string {
+	_cgroupMu.RLock()
+	defer _cgroupMu.RUnlock()
+
+	return _cgroupRoot
+}
+
+// CgroupMemoryLimit returns the memory limit in bytes of the cgroup of
+// process, which is the lowest one of it and its ancestors, it supports
+// both cgroup v1 and v2. The second value is false if there is no limit
+// or it cannot be found.
+func CgroupMemoryLimit() (uint64, bool) {
+	root := filepath.Clean(cgroupRoot())
+	v2, v1 := cgroupPaths()
+
+	limit, ok, found := lowestCgroupLimit(root, v2, _cgroupV2MemoryLimit, func(value string) (uint64, bool) {
+		if value == "max" {
+			return 0, false
+		}
+
+		v, err := strconv.ParseUint(value, 10, 64)
+		return v, err == nil
+	})
+	if found {
+		return limit, ok
+	}
+
+	limit, ok, _ = lowestCgroupLimit(filepath.Join(root, _cgroupV1Memory), v1, _cgroupV1MemoryLimit, func(value string) (uint64, bool) {
+		v, err := strconv.ParseUint(value, 10, 64)
+		return v, err == nil && v < _cgroupV1Unlimited
+	})
+	return limit, ok
+}
+
+// cgroupPaths returns the cgroup of process in the v2 hierarchy and
+// the v1 memory hierarchy, they are "/" if unknown.
+func cgroupPaths() (string, string) {
+	v2, v1 := "/", "/"
+	data, err := os.ReadFile(_procSelfCgroup)
+	if err != nil {
+		return v2, v1
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[0] == "0" && fields[1] == "" {
+			v2 = fields[2]
+			continue
+		}
+
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == _cgroupV1Memory {
+				v1 = fields[2]
+			}
+		}
+	}
+
+	return v2, v1
+}
+
+// lowestCgroupLimit reads name in the cgroup at path under root and its
+// ancestors, and returns the lowest limit parsed. The last value is
+// false if name is not found in any of them.
+func lowestCgroupLimit(root, path, name string, parse func(string) (uint64, bool)) (uint64, bool, bool) {
+	dir := filepath.Join(root, path)
+	if !strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		dir = root
+	}
+
+	var limit uint64
+	limited, found := false, false
+	for {
+		if value, err := readCgroupValue(filepath.Join(dir, name)); err == nil {
+			found = true
+			if v, ok := parse(value); ok && (!limited || v < limit) {
+				limit, limited = v, true
+			}
+		}
+
+		if dir == root {
+			return limit, limited, found
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func readCgroupValue(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// IsMemoryBacked checks if the file system type keeps its data in memory.
+func IsMemoryBacked(fsType string) bool {
+	return fsType == "TMPFS" || fsType == "RAMFS"
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	defer SetCgroupRoot(cgroupRoot())
+
+	v2 := t.TempDir()
+	if err := os.WriteFile(filepath.Join(v2, _cgroupV2MemoryLimit), []byte("1048576\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	SetCgroupRoot(v2)
+	if limit, ok := CgroupMemoryLimit(); !ok || limit != 1048576 {
+		t.Fatalf("Expected limit: 1048576, got: %d", limit)
+	}
+
+	v1 := t.TempDir()
+	if err := os.Mkdir(filepath.Join(v1, "memory"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(v1, _cgroupV1Memory, _cgroupV1MemoryLimit), []byte("9223372036854771712\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	SetCgroupRoot(v1)
+	if limit, ok := CgroupMemoryLimit(); ok {
+		t.Fatalf("Unexpected limit: %d", limit)
+	}
+
+	// tmpfs capacity should be bounded by the limit
+	SetCgroupRoot(v2)
+	fsInfo, err := GetFSInfo("/dev/shm")
+	if err != nil || !fsInfo.MemoryBacked() {
+		t.Skip("no tmpfs at /dev/shm")
+	}
+
+	if fsInfo.CapacityBytes() > 1048576 {
+		t.Fatalf("Expected capacity <= 1.00 MiB, got: %s", fsInfo.Capacity())
+	}
+
+	if fsInfo.FreeBytes() > fsInfo.CapacityBytes() {
+		t.Fatalf("Expected free <= %s, got: %s", fsInfo.Capacity(), fsInfo.Free())
+	}
+}
+
+func TestCgroupPath(t *testing.T) {
+	defer SetCgroupRoot(cgroupRoot())
+	defer func(path string) { _procSelfCgroup = path }(_procSelfCgroup)
+
+	root := t.TempDir()
+	for path, limit := range map[string]string{
+		"memory.max":                          "max",
+		"app.slice/memory.max":                "2097152",
+		"app.slice/svc/memory.max":            "max",
+		"other.slice/memory.max":              "1024",
+		"memory/docker/memory.limit_in_bytes": "1048576",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(root, path), []byte(limit+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_procSelfCgroup = filepath.Join(root, "cgroup")
+	SetCgroupRoot(root)
+
+	// the limit of the parent applies to the cgroup of process
+	if err := os.WriteFile(_procSelfCgroup, []byte("0::/app.slice/svc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if limit, ok := CgroupMemoryLimit(); !ok || limit != 2097152 {
+		t.Fatalf("Expected limit: 2097152, got: %d", limit)
+	}
+
+	if err := os.Remove(filepath.Join(root, _cgroupV2MemoryLimit)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "app.slice")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(_procSelfCgroup, []byte("5:cpu,memory:/docker\n0::/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if limit, ok := CgroupMemoryLimit(); !ok || limit != 1048576 {
+		t.Fatalf("Expected limit of v1: 1048576, got: %d", limit)
+	}
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"io/fs"
+	"path/filepath"
 	"strconv"
 	"syscall"
 )
@@ -22,6 +24,7 @@ var _fsType = map[string]string{
 	"2fc12fc1": "zfs",
 	"ff534d42": "cifs",
 	"53464846": "wslfs",
+	"858458f6": "RAMFS",
 }
 
 func GetFSType(fsType int64) string {
@@ -35,12 +38,13 @@ func GetFSType(fsType int64) string {
 }
 
 type FSInfo struct {
-	total  uint64 // total size of the volume / disk
-	used   uint64 // free size of the volume / disk
-	free   uint64 // free size of the volume / disk
-	files  uint64 //  total inodes available
-	ffree  uint64 // free inodes available
-	fsType string // file system type
+	total    uint64 // total size of the volume / disk
+	used     uint64 // free size of the volume / disk
+	free     uint64 // free size of the volume / disk
+	capacity uint64 // effective size, bounded by cgroup memory limit if memory-backed
+	files    uint64 //  total inodes available
+	ffree    uint64 // free inodes available
+	fsType   string // file system type
 }
 
 func GetFSInfo(path string) (*FSInfo, error) {
@@ -50,20 +54,46 @@ func GetFSInfo(path string) (*FSInfo, error) {
 		return nil, err
 	}
 
-	return &FSInfo{
+	info := &FSInfo{
 		total:  s.Blocks * uint64(s.Bsize),
 		free:   s.Bfree * uint64(s.Bsize),
 		used:   (s.Blocks - s.Bfree) * uint64(s.Bsize),
 		files:  s.Files,
 		ffree:  s.Ffree,
 		fsType: GetFSType(s.Type),
-	}, nil
+	}
+
+	info.capacity = info.total
+	if IsMemoryBacked(info.fsType) {
+		// tmpfs pages are charged to the cgroup memory, so the limit
+		// bounds the real capacity. ramfs has no size at all.
+		if limit, ok := CgroupMemoryLimit(); ok {
+			if info.capacity == 0 || limit < info.capacity {
+				info.capacity = limit
+			}
+		}
+	}
+
+	// the free space is bounded by the capacity as well
+	if info.capacity != info.total {
+		available := uint64(0)
+		if info.used < info.capacity {
+			available = info.capacity - info.used
+		}
+
+		if available < info.free {
+			info.free = available
+		}
+	}
+
+	return info, nil
 }
 
 func (f *FSInfo) Total() string {
 	return FormatByte(float64(f.total)).Show()
 }
 
+// Free returns the free size, which is bounded by Capacity.
 func (f *FSInfo) Free() string {
 	return FormatByte(float64(f.free)).Show()
 }
@@ -72,10 +102,20 @@ func (f *FSInfo) Used() string {
 	return FormatByte(float64(f.used)).Show()
 }
 
+// Capacity returns the effective size, which is the minimum of
+// the total size and the cgroup memory limit for tmpfs / ramfs.
+func (f *FSInfo) Capacity() string {
+	return FormatByte(float64(f.capacity)).Show()
+}
+
 func (f *FSInfo) TotalBytes() uint64 {
 	return f.total
 }
 
+func (f *FSInfo) CapacityBytes() uint64 {
+	return f.capacity
+}
+
 func (f *FSInfo) FreeBytes() uint64 {
 	return f.free
 }
@@ -95,3 +135,57 @@ func (f *FSInfo) FreeInodes() uint64 {
 func (f *FSInfo) Type() string {
 	return f.fsType
 }
+
+func (f *FSInfo) MemoryBacked() bool {
+	return IsMemoryBacked(f.fsType)
+}
+
+// DirUsage walks the directory tree rooted at path and sums
+// up the size of all regular files.
+func DirUsage(path string) (uint64, error) {
+	usage, _, err := walkUsage(path, nil)
+	return usage, err
+}
+
+// walkUsage returns the size of all regular files and the number
+// of inodes under path, only the files counted by owned are summed
+// if it is not nil.
+func walkUsage(path string, owned func(string, fs.FileInfo) (bool, error)) (uint64, uint64, error) {
+	var usage, inodes uint64
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() && owned == nil {
+			inodes++
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if owned != nil {
+			ok, err := owned(p, info)
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		inodes++
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		usage += uint64(info.Size())
+		return nil
+	})
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return usage, inodes, nil
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/chao77977/pkg"
+)
+
+// Progress represents the state of a transfer.
+type Progress struct {
+	Transferred *FormatSize
+	Total       *FormatSize // nil if unknown
+	Rate        *FormatSize // per second
+	ETA         pkg.HumaneDuration
+}
+
+func (p Progress) String() string {
+	if p.Total == nil {
+		return fmt.Sprintf("%s, %s/s", p.Transferred.Show(), p.Rate.Show())
+	}
+
+	return fmt.Sprintf("%s / %s, %s/s, ETA %s",
+		p.Transferred.Show(), p.Total.Show(), p.Rate.Show(), p.ETA)
+}
+
+type ProgressFunc func(Progress)
+
+type meterConfigurer func(*meter)
+
+// WithRateLimit throttles the transfer to rate per second.
+func WithRateLimit(rate *FormatSize) meterConfigurer {
+	return func(m *meter) {
+		if rate != nil {
+			m.limit = rate.Bytes()
+		}
+	}
+}
+
+// WithTotal sets the expected size in bytes to estimate the ETA.
+func WithTotal(total int64) meterConfigurer {
+	return func(m *meter) {
+		m.total = total
+	}
+}
+
+// WithProgress calls fn at most once every interval, and once
+// more when the transfer is done.
+func WithProgress(fn ProgressFunc, interval time.Duration) meterConfigurer {
+	return func(m *meter) {
+		m.progress = fn
+		m.interval = interval
+	}
+}
+
+const _meterWindow = time.Second
+
+type meter struct {
+	limit    float64 // bytes per second, 0 means unlimited
+	total    int64
+	progress ProgressFunc
+	interval time.Duration
+
+	mu          sync.Mutex
+	start       time.Time
+	count       int64
+	windowStart time.Time
+	windowCount int64
+	rate        float64
+	lastReport  time.Time
+	done        bool
+}
+
+func newMeter(configs ...meterConfigurer) *meter {
+	m := &meter{}
+	for _, config := range configs {
+		config(m)
+	}
+
+	return m
+}
+
+// chunk limits the size of one read or write, so that throttling
+// sleeps at most about one second at a time.
+func (m *meter) chunk(n int) int {
+	if m.limit > 0 && float64(n) > m.limit {
+		n = int(m.limit)
+		if n < 1 {
+			n = 1
+		}
+	}
+
+	return n
+}
+
+func (m *meter) begin() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.start.IsZero() {
+		m.start = time.Now()
+		m.windowStart = m.start
+	}
+}
+
+func (m *meter) add(n int, eof bool) {
+	m.mu.Lock()
+
+	now := time.Now()
+	m.count += int64(n)
+	m.windowCount += int64(n)
+	if elapsed := now.Sub(m.windowStart); elapsed >= _meterWindow {
+		m.rate = float64(m.windowCount) / elapsed.Seconds()
+		m.windowStart, m.windowCount = now, 0
+	}
+
+	var wait time.Duration
+	if m.limit > 0 {
+		expected := time.Duration(float64(m.count) / m.limit * float64(time.Second))
+		wait = expected - now.Sub(m.start)
+	}
+
+	finished := eof || (m.total > 0 && m.count >= m.total)
+	report := m.progress != nil && !m.done &&
+		(finished || now.Sub(m.lastReport) >= m.interval)
+	if report {
+		m.lastReport = now
+		m.done = finished
+	}
+
+	var p Progress
+	if report {
+		p = m.snapshot(now)
+	}
+
+	m.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	if report {
+		m.progress(p)
+	}
+}
+
+func (m *meter) throughput(now time.Time) float64 {
+	if m.rate > 0 {
+		return m.rate
+	}
+
+	// the first window is not complete yet
+	if elapsed := now.Sub(m.start); elapsed > 0 {
+		return float64(m.count) / elapsed.Seconds()
+	}
+
+	return 0
+}
+
+func (m *meter) snapshot(now time.Time) Progress {
+	rate := m.throughput(now)
+	p := Progress{
+		Transferred: FormatByte(float64(m.count)),
+		Rate:        FormatByte(rate),
+	}
+
+	if m.total > 0 {
+		p.Total = FormatByte(float64(m.total))
+		if left := m.total - m.count; left > 0 && rate > 0 {
+			p.ETA = pkg.HumaneDuration(float64(left) / rate * float64(time.Second))
+		}
+	}
+
+	return p
+}
+
+func (m *meter) Count() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.count
+}
+
+func (m *meter) Throughput() *FormatSize {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return FormatByte(m.throughput(time.Now()))
+}
+
+func (m *meter) Progress() Progress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.snapshot(time.Now())
+}
+
+// CountingReader counts the bytes read, and optionally throttles
+// and reports the progress.
+type CountingReader struct {
+	*meter
+	r io.Reader
+}
+
+func NewCountingReader(r io.Reader, configs ...meterConfigurer) *CountingReader {
+	return &CountingReader{meter: newMeter(configs...), r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	c.begin()
+	n, err := c.r.Read(p[:c.chunk(len(p))])
+	c.add(n, err == io.EOF)
+	return n, err
+}
+
+// CountingWriter counts the bytes written, and optionally throttles
+// and reports the progress.
+type CountingWriter struct {
+	*meter
+	w io.Writer
+}
+
+func NewCountingWriter(w io.Writer, configs ...meterConfigurer) *CountingWriter {
+	return &CountingWriter{meter: newMeter(configs...), w: w}
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	c.begin()
+
+	written := 0
+	for len(p) > 0 {
+		n, err := c.w.Write(p[:c.chunk(len(p))])
+		c.add(n, false)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		// no progress, as io.Copy
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
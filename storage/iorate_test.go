@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCountingReader(t *testing.T) {
+	rate, err := ParseSize("4MiB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 1024*1024)
+	var last Progress
+	r := NewCountingReader(bytes.NewReader(data),
+		WithRateLimit(rate),
+		WithTotal(int64(len(data))),
+		WithProgress(func(p Progress) { last = p }, 100*time.Millisecond))
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(data)) || r.Count() != n {
+		t.Fatalf("Expected count: %d, got: %d", len(data), r.Count())
+	}
+
+	// 1 MiB at 4 MiB/s takes about 250ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("Unexpected transfer time %s with rate limit %s", elapsed, rate.Show())
+	}
+
+	if last.Transferred == nil || last.Transferred.Compare(last.Total) != 0 {
+		t.Fatalf("Unexpected final progress: %v", last)
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCountingWriter(&buf)
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Count() != 5 || buf.String() != "hello" {
+		t.Fatalf("Expected count: 5, got: %d", w.Count())
+	}
+}
+
+type stuckWriter struct{}
+
+func (stuckWriter) Write(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestCountingWriterShortWrite(t *testing.T) {
+	w := NewCountingWriter(stuckWriter{})
+	if n, err := w.Write([]byte("hello")); n != 0 || err != io.ErrShortWrite {
+		t.Fatalf("Expected short write, got: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type QuotaType int
+
+const (
+	UserQuota QuotaType = iota
+	GroupQuota
+	ProjectQuota
+)
+
+func (t QuotaType) String() string {
+	switch t {
+	case UserQuota:
+		return "user"
+	case GroupQuota:
+		return "group"
+	case ProjectQuota:
+		return "project"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrQuotaNotSupported = errors.New("quota is not supported")
+
+// the time when the usage of a path is first found over soft limit
+// by walking it, the grace period starts from it.
+var _softExceeded sync.Map
+
+// QuotaLimit represents the configured limits used when quotas are not
+// enabled on the file system. A nil size means no limit.
+type QuotaLimit struct {
+	Soft  *FormatSize
+	Hard  *FormatSize
+	Grace time.Duration
+}
+
+// QuotaUsage represents how much of a volume is consumed against quota.
+type QuotaUsage struct {
+	Used  *FormatSize
+	Soft  *FormatSize // nil means no limit
+	Hard  *FormatSize // nil means no limit
+	Grace time.Duration
+	// GraceExpires is the time when the soft limit is enforced as a hard
+	// one, it is zero if the usage is under soft limit or it is unknown.
+	GraceExpires time.Time
+
+	Inodes     uint64
+	InodeSoft  uint64 // 0 means no limit
+	InodeHard  uint64 // 0 means no limit
+	InodeGrace time.Duration
+
+	// Fallback is true if the usage is computed by walking the path
+	// rather than read from the file system quota.
+	Fallback bool
+}
+
+func (q *QuotaUsage) OverSoft() bool {
+	return q.Soft != nil && q.Used.Compare(q.Soft) > 0
+}
+
+func (q *QuotaUsage) OverHard() bool {
+	return q.Hard != nil && q.Used.Compare(q.Hard) > 0
+}
+
+// GetQuota reads the quota of id from the file system containing path,
+// it returns ErrQuotaNotSupported if quotas are not enabled.
+func GetQuota(path string, typ QuotaType, id uint32) (*QuotaUsage, error) {
+	return getQuota(path, typ, id)
+}
+
+// GetUsageQuota computes the usage of path by walking it and compares
+// it with the configured limit. GraceExpires is counted from the first
+// call finding the usage over soft limit in the process.
+func GetUsageQuota(path string, limit *QuotaLimit) (*QuotaUsage, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+
+	return getUsageQuota(path, key, limit, nil)
+}
+
+// getUsageQuota walks path counting the files accepted by owned, the
+// grace period is tracked by key.
+func getUsageQuota(path, key string, limit *QuotaLimit, owned func(string, fs.FileInfo) (bool, error)) (*QuotaUsage, error) {
+	usage, inodes, err := walkUsage(path, owned)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &QuotaUsage{
+		Used:     FormatByte(float64(usage)),
+		Inodes:   inodes,
+		Fallback: true,
+	}
+
+	if limit != nil {
+		q.Soft = limit.Soft
+		q.Hard = limit.Hard
+		q.Grace = limit.Grace
+	}
+
+	if q.OverSoft() && q.Grace > 0 {
+		since, _ := _softExceeded.LoadOrStore(key, time.Now())
+		q.GraceExpires = since.(time.Time).Add(q.Grace)
+	} else {
+		_softExceeded.Delete(key)
+	}
+
+	return q, nil
+}
+
+// QueryQuota reads the quota from the file system, and falls back
+// to walking path if quotas are not supported. The fallback only
+// counts the files owned by id of typ, like the file system does.
+func QueryQuota(path string, typ QuotaType, id uint32, limit *QuotaLimit) (*QuotaUsage, error) {
+	q, err := GetQuota(path, typ, id)
+	if err == nil {
+		return q, nil
+	}
+
+	if !errors.Is(err, ErrQuotaNotSupported) {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+
+	key := fmt.Sprintf("%s:%s:%d", abs, typ, id)
+	return getUsageQuota(path, key, limit, func(p string, info fs.FileInfo) (bool, error) {
+		// the project of special files is not read, which opens them
+		if typ == ProjectQuota && !info.Mode().IsRegular() && !info.IsDir() {
+			return false, nil
+		}
+
+		owner, err := quotaOwner(p, info, typ)
+		if err != nil {
+			return false, err
+		}
+
+		return owner == id, nil
+	})
+}
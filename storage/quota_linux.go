@@ -0,0 +1,252 @@
+//go:build linux
+
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	_qGetInfo  = 0x800005
+	_qGetQuota = 0x800007
+
+	// quota block limits are in units of 1 KiB
+	_qBlockSize = 1024
+
+	_fsIocFsGetXattr = 0x801c581f
+)
+
+// ifDqblk is struct if_dqblk in linux/quota.h
+type ifDqblk struct {
+	bHardLimit uint64
+	bSoftLimit uint64
+	curSpace   uint64
+	iHardLimit uint64
+	iSoftLimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+	_          uint32
+}
+
+// ifDqinfo is struct if_dqinfo in linux/quota.h
+type ifDqinfo struct {
+	bGrace uint64
+	iGrace uint64
+	flags  uint32
+	valid  uint32
+}
+
+// fsxattr is struct fsxattr in linux/fs.h
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+func qcmd(cmd int, typ QuotaType) int {
+	return cmd<<8 | int(typ)&0xff
+}
+
+func quotactl(cmd int, special string, id uint32, addr unsafe.Pointer) error {
+	dev, err := syscall.BytePtrFromString(special)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL, uintptr(cmd),
+		uintptr(unsafe.Pointer(dev)), uintptr(id), uintptr(addr), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func getQuota(path string, typ QuotaType, id uint32) (*QuotaUsage, error) {
+	special, err := mountSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQuotaNotSupported, err)
+	}
+
+	dq := ifDqblk{}
+	if err := quotactl(qcmd(_qGetQuota, typ), special, id, unsafe.Pointer(&dq)); err != nil {
+		return nil, makeQuotaError(err)
+	}
+
+	info := ifDqinfo{}
+	if err := quotactl(qcmd(_qGetInfo, typ), special, 0, unsafe.Pointer(&info)); err != nil {
+		return nil, makeQuotaError(err)
+	}
+
+	q := &QuotaUsage{
+		Used:       FormatByte(float64(dq.curSpace)),
+		Grace:      time.Duration(info.bGrace) * time.Second,
+		Inodes:     dq.curInodes,
+		InodeSoft:  dq.iSoftLimit,
+		InodeHard:  dq.iHardLimit,
+		InodeGrace: time.Duration(info.iGrace) * time.Second,
+	}
+
+	if dq.bSoftLimit > 0 {
+		q.Soft = FormatByte(float64(dq.bSoftLimit * _qBlockSize))
+	}
+
+	if dq.bHardLimit > 0 {
+		q.Hard = FormatByte(float64(dq.bHardLimit * _qBlockSize))
+	}
+
+	if dq.bTime > 0 {
+		q.GraceExpires = time.Unix(int64(dq.bTime), 0)
+	}
+
+	return q, nil
+}
+
+func makeQuotaError(err error) error {
+	switch {
+	case errors.Is(err, syscall.ENOSYS),
+		errors.Is(err, syscall.ENOTSUP),
+		errors.Is(err, syscall.ESRCH),
+		errors.Is(err, syscall.ENOENT),
+		errors.Is(err, syscall.ENOTBLK),
+		errors.Is(err, syscall.ENODEV),
+		errors.Is(err, syscall.EINVAL):
+		return fmt.Errorf("%w: %v", ErrQuotaNotSupported, err)
+	default:
+		return err
+	}
+}
+
+// mountSource finds the device of the mount point containing path.
+func mountSource(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var mountPoint, source string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+
+		if len(fields) < 5 || sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+
+		mp := unescapeMountPath(fields[4])
+		if !isSubPath(path, mp) || len(mp) < len(mountPoint) {
+			continue
+		}
+
+		mountPoint, source = mp, fields[sep+2]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(source, "/") {
+		return "", fmt.Errorf("no block device found for '%s'", path)
+	}
+
+	return source, nil
+}
+
+func isSubPath(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+
+	return strings.HasPrefix(path, dir+"/")
+}
+
+// unescapeMountPath decodes the octal escapes, e.g. '\040' for space.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// quotaOwner returns the id of typ owning the file at path.
+func quotaOwner(path string, info fs.FileInfo, typ QuotaType) (uint32, error) {
+	if typ == ProjectQuota {
+		return ProjectID(path)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no owner of '%s'", path)
+	}
+
+	if typ == GroupQuota {
+		return stat.Gid, nil
+	}
+
+	return stat.Uid, nil
+}
+
+// ProjectID returns the project id of path used by project quotas.
+func ProjectID(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	attr := fsxattr{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		_fsIocFsGetXattr, uintptr(unsafe.Pointer(&attr)))
+	if errno != 0 {
+		return 0, makeQuotaError(errno)
+	}
+
+	return attr.projid, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package storage
+
+import "io/fs"
+
+func getQuota(path string, typ QuotaType, id uint32) (*QuotaUsage, error) {
+	return nil, ErrQuotaNotSupported
+}
+
+func quotaOwner(path string, info fs.FileInfo, typ QuotaType) (uint32, error) {
+	return 0, ErrQuotaNotSupported
+}
+
+func ProjectID(path string) (uint32, error) {
+	return 0, ErrQuotaNotSupported
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGetUsageQuota(t *testing.T) {
+	path := t.TempDir()
+	if err := os.WriteFile(filepath.Join(path, "data"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	soft, _ := Format(1, "KiB")
+	hard, _ := Format(4, "KiB")
+	q, err := QueryQuota(path, ProjectQuota, 0, &QuotaLimit{
+		Soft:  soft,
+		Hard:  hard,
+		Grace: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !q.Fallback {
+		t.Skip("quota is enabled on", path)
+	}
+
+	if q.Used.Bytes() != 2048 {
+		t.Fatalf("Expected usage: 2.00 KiB, got: %s", q.Used.Show())
+	}
+
+	if !q.OverSoft() || q.OverHard() {
+		t.Fatalf("Unexpected usage %s against soft %s and hard %s",
+			q.Used.Show(), q.Soft.Show(), q.Hard.Show())
+	}
+
+	if q.Inodes != 2 {
+		t.Fatalf("Expected inodes: 2, got: %d", q.Inodes)
+	}
+
+	// the grace period starts when the soft limit is first exceeded
+	again, err := QueryQuota(path, ProjectQuota, 0, &QuotaLimit{Soft: soft, Grace: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.GraceExpires.IsZero() || !again.GraceExpires.Equal(q.GraceExpires) {
+		t.Fatalf("Expected grace expires: %s, got: %s", q.GraceExpires, again.GraceExpires)
+	}
+}
+
+func TestQueryQuotaOwner(t *testing.T) {
+	path := t.TempDir()
+	if err := os.WriteFile(filepath.Join(path, "mine"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, "other"), make([]byte, 1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := uint32(os.Getuid())
+	if err := os.Chown(filepath.Join(path, "other"), int(uid+1), -1); err != nil {
+		t.Skip("cannot change owner:", err)
+	}
+
+	q, err := QueryQuota(path, UserQuota, uid, nil)
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("quota is not permitted on", path)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !q.Fallback {
+		t.Skip("quota is enabled on", path)
+	}
+
+	// the directory and mine
+	if q.Used.Bytes() != 2048 || q.Inodes != 2 {
+		t.Fatalf("Expected usage: 2.00 KiB of 2 inodes, got: %s of %d", q.Used.Show(), q.Inodes)
+	}
+
+	q, err = QueryQuota(path, UserQuota, uid+1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Used.Bytes() != 1024 || q.Inodes != 1 {
+		t.Fatalf("Expected usage: 1.00 KiB of 1 inode, got: %s of %d", q.Used.Show(), q.Inodes)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 )
 
@@ -94,6 +95,27 @@ func Format(size float64, unit string) (*FormatSize, error) {
 	return &FormatSize{size: size, index: index}, nil
 }
 
+// ParseSize parses size string with unit, e.g. "50MiB", "1.5 GiB",
+// a size without unit is in bytes.
+func ParseSize(s string) (*FormatSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	number, unit := s, "B"
+	if i >= 0 {
+		number, unit = s[:i], strings.TrimSpace(s[i:])
+	}
+
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	return Format(size, unit)
+}
+
 func FormatByte(size float64) *FormatSize {
 	f, _ := Format(size, "B")
 	return f
@@ -104,6 +126,11 @@ func (f *FormatSize) Unit() string {
 	return unit
 }
 
+func (f *FormatSize) Bytes() float64 {
+	ff, _ := f.convert("B")
+	return ff.size
+}
+
 func (f *FormatSize) Truncate(precision uint) float64 {
 	precision = fltDig(precision)
 	return float64(uint64(f.size*math.Pow(10,
@@ -81,3 +81,27 @@ func TestCompare(t *testing.T) {
 		t.Fatalf("Unexpected compare %s < %s", x.Show(), y.Show())
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	x, err := ParseSize("1.5 GiB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x.Show() != "1.50 GiB" {
+		t.Fatalf("Expected size: 1.50 GiB, got: %s", x.Show())
+	}
+
+	x, err = ParseSize("2048")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x.Show() != "2.00 KiB" {
+		t.Fatalf("Expected size: 2.00 KiB, got: %s", x.Show())
+	}
+
+	if _, err = ParseSize("50MB"); err == nil {
+		t.Fatalf("Unexpected size unit: MB")
+	}
+}
@@ -0,0 +1,512 @@
+//go:build linux
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// WatchOp describes a set of file operations.
+type WatchOp uint32
+
+const (
+	WatchCreate WatchOp = 1 << iota
+	WatchWrite
+	WatchRemove
+	WatchRename
+	WatchChmod
+	// WatchOverflow means the kernel event queue overflowed and
+	// some events are lost, the watched tree should be rescanned.
+	WatchOverflow
+)
+
+var _watchOps = []struct {
+	op   WatchOp
+	name string
+}{
+	{WatchCreate, "CREATE"},
+	{WatchWrite, "WRITE"},
+	{WatchRemove, "REMOVE"},
+	{WatchRename, "RENAME"},
+	{WatchChmod, "CHMOD"},
+	{WatchOverflow, "OVERFLOW"},
+}
+
+func (op WatchOp) String() string {
+	names := []string{}
+	for _, o := range _watchOps {
+		if op&o.op != 0 {
+			names = append(names, o.name)
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// WatchEvent represents the coalesced operations happened on a path.
+type WatchEvent struct {
+	Path string
+	Op   WatchOp
+}
+
+const _inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_MOVE_SELF | syscall.IN_ATTRIB
+
+type watcherConfigurer func(*Watcher)
+
+// WithRecursive watches all the sub directories, including the ones
+// created or moved in after the watcher started.
+func WithRecursive() watcherConfigurer {
+	return func(w *Watcher) {
+		w.recursive = true
+	}
+}
+
+// WithDebounce coalesces events on the same path until there is
+// no more event within d.
+func WithDebounce(d time.Duration) watcherConfigurer {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// WithFilters only delivers events whose file name, or path relative
+// to the root, matches one of the glob patterns.
+func WithFilters(patterns ...string) watcherConfigurer {
+	return func(w *Watcher) {
+		w.filters = append(w.filters, patterns...)
+	}
+}
+
+// Watcher watches filesystem changes with inotify.
+type Watcher struct {
+	root      string
+	recursive bool
+	debounce  time.Duration
+	filters   []string
+
+	fd   int
+	file *os.File
+
+	mu      sync.Mutex
+	watches map[int]string // wd -> path
+	paths   map[string]int // path -> wd
+
+	raw    chan WatchEvent
+	events chan WatchEvent
+	errors chan error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher starts watching root, events are delivered until ctx is
+// done or the watcher is closed.
+func NewWatcher(ctx context.Context, root string, configs ...watcherConfigurer) (*Watcher, error) {
+	w := &Watcher{
+		root:    filepath.Clean(root),
+		watches: make(map[int]string),
+		paths:   make(map[string]int),
+		raw:     make(chan WatchEvent, 128),
+		events:  make(chan WatchEvent, 128),
+		errors:  make(chan error, 16),
+		done:    make(chan struct{}),
+	}
+
+	for _, config := range configs {
+		config(w)
+	}
+
+	for _, pattern := range w.filters {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	// the non-blocking fd is registered to the runtime poller,
+	// so closing the file unblocks the pending read. Keep the raw
+	// fd since File.Fd() would put it back into blocking mode.
+	w.fd = fd
+	w.file = os.NewFile(uintptr(fd), "inotify")
+
+	if err := w.addTree(w.root, false); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	go w.readEvents()
+	go w.dispatch(ctx)
+
+	return w, nil
+}
+
+// Events returns the channel of coalesced events, it is closed
+// when the watcher stops.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Errors returns the channel of errors occurred while watching.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close stops the watcher and releases the inotify instance.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) addWatch(path string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, path, _inotifyMask)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if old, ok := w.watches[wd]; ok && old != path {
+		delete(w.paths, old)
+	}
+
+	w.watches[wd] = path
+	w.paths[path] = wd
+	return nil
+}
+
+// addTree watches path and, if recursive, all its sub directories.
+// Files found in newly created directories are reported as created
+// since they may have been written before the watch was added.
+func (w *Watcher) addTree(path string, report bool) error {
+	if !w.recursive {
+		return w.addWatch(path)
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p != path {
+				return nil
+			}
+			return err
+		}
+
+		if report && p != path {
+			w.send(WatchEvent{Path: p, Op: WatchCreate})
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if err := w.addWatch(p); err != nil && !errors.Is(err, syscall.ENOENT) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// removeTree forgets the watches on path and everything below it.
+func (w *Watcher) removeTree(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	prefix := path + string(filepath.Separator)
+	for p, wd := range w.paths {
+		if p == path || strings.HasPrefix(p, prefix) {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, p)
+			delete(w.watches, wd)
+		}
+	}
+}
+
+// renameTree updates the watched paths after a directory is renamed
+// inside the tree, the watch descriptors themselves stay valid.
+func (w *Watcher) renameTree(from, to string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	prefix := from + string(filepath.Separator)
+	for p, wd := range w.paths {
+		if p != from && !strings.HasPrefix(p, prefix) {
+			continue
+		}
+
+		np := to + strings.TrimPrefix(p, from)
+		delete(w.paths, p)
+		w.paths[np] = wd
+		w.watches[wd] = np
+	}
+}
+
+func (w *Watcher) pathOf(wd int) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path, ok := w.watches[wd]
+	return path, ok
+}
+
+func (w *Watcher) forget(wd int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if path, ok := w.watches[wd]; ok {
+		delete(w.watches, wd)
+		if w.paths[path] == wd {
+			delete(w.paths, path)
+		}
+	}
+}
+
+func (w *Watcher) send(ev WatchEvent) {
+	select {
+	case w.raw <- ev:
+	case <-w.done:
+	}
+}
+
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func (w *Watcher) readEvents() {
+	defer close(w.raw)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.sendError(err)
+			}
+			return
+		}
+
+		w.handleEvents(buf[:n])
+	}
+}
+
+func (w *Watcher) handleEvents(buf []byte) {
+	// directories moved from, keyed by cookie
+	movedFrom := map[uint32]string{}
+
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameBuf := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+		offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+		mask := raw.Mask
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			w.send(WatchEvent{Path: w.root, Op: WatchOverflow})
+			if err := w.addTree(w.root, false); err != nil {
+				w.sendError(err)
+			}
+			continue
+		}
+
+		dir, ok := w.pathOf(int(raw.Wd))
+		if !ok {
+			continue
+		}
+
+		if mask&syscall.IN_IGNORED != 0 {
+			w.forget(int(raw.Wd))
+			continue
+		}
+
+		path := dir
+		if name := strings.TrimRight(string(nameBuf), "\x00"); name != "" {
+			path = filepath.Join(dir, name)
+		}
+
+		isDir := mask&syscall.IN_ISDIR != 0
+		switch {
+		case mask&syscall.IN_MOVED_FROM != 0 && isDir:
+			movedFrom[raw.Cookie] = path
+		case mask&syscall.IN_MOVED_TO != 0 && isDir:
+			if from, ok := movedFrom[raw.Cookie]; ok {
+				delete(movedFrom, raw.Cookie)
+				w.renameTree(from, path)
+			} else if w.recursive {
+				if err := w.addTree(path, true); err != nil {
+					w.sendError(err)
+				}
+			}
+		case mask&syscall.IN_CREATE != 0 && isDir && w.recursive:
+			if err := w.addTree(path, true); err != nil {
+				w.sendError(err)
+			}
+		}
+
+		if op := makeWatchOp(mask); op != 0 {
+			w.send(WatchEvent{Path: path, Op: op})
+		}
+	}
+
+	// directories moved out of the tree are no longer watched
+	for _, from := range movedFrom {
+		w.removeTree(from)
+	}
+}
+
+func makeWatchOp(mask uint32) WatchOp {
+	var op WatchOp
+	if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		op |= WatchCreate
+	}
+
+	if mask&(syscall.IN_MODIFY|syscall.IN_CLOSE_WRITE) != 0 {
+		op |= WatchWrite
+	}
+
+	if mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF) != 0 {
+		op |= WatchRemove
+	}
+
+	if mask&(syscall.IN_MOVED_FROM|syscall.IN_MOVE_SELF) != 0 {
+		op |= WatchRename
+	}
+
+	if mask&syscall.IN_ATTRIB != 0 {
+		op |= WatchChmod
+	}
+
+	return op
+}
+
+func (w *Watcher) match(path string) bool {
+	if len(w.filters) == 0 {
+		return true
+	}
+
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		rel = path
+	}
+
+	for _, pattern := range w.filters {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pendingEvent is an event coalesced until its deadline.
+type pendingEvent struct {
+	op       WatchOp
+	deadline time.Time
+}
+
+// dispatch coalesces the raw events on each path and delivers them to
+// the events channel until ctx is done.
+func (w *Watcher) dispatch(ctx context.Context) {
+	defer close(w.events)
+	defer close(w.done)
+	defer w.file.Close()
+
+	pending := map[string]*pendingEvent{}
+	order := []string{}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	// flush delivers the events due at now, or all of them if now is
+	// zero, and schedules the timer for the earliest one left.
+	flush := func(now time.Time) bool {
+		left := order[:0]
+		var next time.Time
+		for _, path := range order {
+			ev := pending[path]
+			if !now.IsZero() && ev.deadline.After(now) {
+				left = append(left, path)
+				if next.IsZero() || ev.deadline.Before(next) {
+					next = ev.deadline
+				}
+				continue
+			}
+
+			select {
+			case w.events <- WatchEvent{Path: path, Op: ev.op}:
+			case <-ctx.Done():
+				return false
+			}
+			delete(pending, path)
+		}
+		order = left
+
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.raw:
+			if !ok {
+				flush(time.Time{})
+				return
+			}
+
+			if ev.Op != WatchOverflow && !w.match(ev.Path) {
+				continue
+			}
+
+			p, ok := pending[ev.Path]
+			if !ok {
+				p = &pendingEvent{}
+				pending[ev.Path] = p
+				order = append(order, ev.Path)
+			}
+			p.op |= ev.Op
+
+			if w.debounce <= 0 {
+				if !flush(time.Time{}) {
+					return
+				}
+				continue
+			}
+
+			// only the first deadline arms the timer, the later ones are
+			// scheduled by flush
+			p.deadline = time.Now().Add(w.debounce)
+			if len(order) == 1 && !ok {
+				timer.Reset(w.debounce)
+			}
+		case <-timer.C:
+			if !flush(time.Now()) {
+				return
+			}
+		}
+	}
+}
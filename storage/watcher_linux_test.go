@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := NewWatcher(ctx, root,
+		WithRecursive(),
+		WithDebounce(50*time.Millisecond),
+		WithFilters("*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(sub, "a.log"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(sub, "a.txt")
+	if err := os.WriteFile(expected, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-w.Events():
+		if ev.Path != expected {
+			t.Fatalf("Expected event on %s, got: %s", expected, ev.Path)
+		}
+
+		if ev.Op&WatchCreate == 0 {
+			t.Fatalf("Expected CREATE, got: %s", ev.Op)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout waiting for event")
+	}
+
+	moved := filepath.Join(root, "moved")
+	if err := os.Rename(sub, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = filepath.Join(moved, "b.txt")
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(expected, []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case ev := <-w.Events():
+			if ev.Path == expected {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout waiting for event in renamed directory")
+		}
+	}
+}
+
+func TestWatcherDebouncePerPath(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w := &Watcher{
+		debounce: 50 * time.Millisecond,
+		raw:      make(chan WatchEvent),
+		events:   make(chan WatchEvent, 16),
+		done:     make(chan struct{}),
+	}
+	go w.dispatch(ctx)
+
+	// the busy path does not hold back the quiet one
+	w.raw <- WatchEvent{Path: "quiet", Op: WatchWrite}
+	for i := 0; i < 20; i++ {
+		w.raw <- WatchEvent{Path: "busy", Op: WatchWrite}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case ev := <-w.events:
+		if ev.Path != "quiet" {
+			t.Fatalf("Expected event on quiet, got: %s", ev.Path)
+		}
+	default:
+		t.Fatal("Expected event on quiet while busy is written")
+	}
+
+	close(w.raw)
+	if ev := <-w.events; ev.Path != "busy" {
+		t.Fatalf("Expected event on busy, got: %s", ev.Path)
+	}
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const _workspaceOwnerFile = ".owner"
+
+var ErrQuotaExceeded = errors.New("workspace quota exceeded")
+
+// WorkspaceManager creates uniquely named scratch directories under
+// root, and removes the ones left behind by dead processes.
+type WorkspaceManager struct {
+	root  string
+	quota *FormatSize
+
+	mu         sync.Mutex
+	workspaces map[string]*Workspace
+}
+
+// NewWorkspaceManager makes sure root exists and sweeps stale workspaces
+// from dead processes. A nil quota means workspaces are unlimited.
+func NewWorkspaceManager(root string, quota *FormatSize) (*WorkspaceManager, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &WorkspaceManager{
+		root:       root,
+		quota:      quota,
+		workspaces: make(map[string]*Workspace),
+	}
+
+	if _, err := m.Sweep(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *WorkspaceManager) Root() string {
+	return m.root
+}
+
+// Create creates a new workspace owned by the current process.
+func (m *WorkspaceManager) Create(prefix string) (*Workspace, error) {
+	path, err := os.MkdirTemp(m.root, prefix+"-*")
+	if err != nil {
+		return nil, err
+	}
+
+	owner := filepath.Join(path, _workspaceOwnerFile)
+	pid := os.Getpid()
+	if err := os.WriteFile(owner, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		os.RemoveAll(path)
+		return nil, err
+	}
+
+	w := &Workspace{
+		m:     m,
+		path:  path,
+		pid:   pid,
+		quota: m.quota,
+	}
+
+	m.mu.Lock()
+	m.workspaces[path] = w
+	m.mu.Unlock()
+
+	return w, nil
+}
+
+// Sweep removes workspaces whose owner process is no longer alive,
+// and returns the removed paths.
+func (m *WorkspaceManager) Sweep() ([]string, error) {
+	entries, err := os.ReadDir(m.root)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(m.root, entry.Name())
+		pid, err := readWorkspaceOwner(path)
+		if err != nil {
+			// not a workspace, or it is still being created
+			continue
+		}
+
+		if processAlive(pid) {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, path)
+	}
+
+	return removed, nil
+}
+
+// Close removes all the workspaces created by this manager.
+func (m *WorkspaceManager) Close() error {
+	m.mu.Lock()
+	workspaces := make([]*Workspace, 0, len(m.workspaces))
+	for _, w := range m.workspaces {
+		workspaces = append(workspaces, w)
+	}
+	m.mu.Unlock()
+
+	var retErr error
+	for _, w := range workspaces {
+		if err := w.Close(); err != nil {
+			retErr = err
+		}
+	}
+
+	return retErr
+}
+
+func (m *WorkspaceManager) untrack(path string) {
+	m.mu.Lock()
+	delete(m.workspaces, path)
+	m.mu.Unlock()
+}
+
+// Workspace represents a temporary directory owned by a process.
+type Workspace struct {
+	m     *WorkspaceManager
+	path  string
+	pid   int
+	quota *FormatSize
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (w *Workspace) Path() string {
+	return w.path
+}
+
+func (w *Workspace) Pid() int {
+	return w.pid
+}
+
+func (w *Workspace) Quota() *FormatSize {
+	return w.quota
+}
+
+// Usage returns the size of all files in the workspace.
+func (w *Workspace) Usage() (*FormatSize, error) {
+	usage, err := DirUsage(w.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatByte(float64(usage)), nil
+}
+
+// CheckQuota returns ErrQuotaExceeded if the usage of workspace
+// is larger than its quota.
+func (w *Workspace) CheckQuota() error {
+	if w.quota == nil {
+		return nil
+	}
+
+	usage, err := w.Usage()
+	if err != nil {
+		return err
+	}
+
+	if usage.Compare(w.quota) > 0 {
+		return fmt.Errorf("%w: %s > %s", ErrQuotaExceeded, usage.Show(), w.quota.Show())
+	}
+
+	return nil
+}
+
+// Close removes the workspace and everything in it.
+func (w *Workspace) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	if err := os.RemoveAll(w.path); err != nil {
+		return err
+	}
+
+	w.closed = true
+	w.m.untrack(w.path)
+	return nil
+}
+
+func readWorkspaceOwner(path string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(path, _workspaceOwnerFile))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
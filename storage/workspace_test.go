@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	quota, err := Format(1, "KiB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewWorkspaceManager(t.TempDir(), quota)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := m.Create("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.CheckQuota(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 2048)
+	if err := os.WriteFile(filepath.Join(w.Path(), "data"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.CheckQuota(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected quota exceeded, got: %v", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(w.Path()); !os.IsNotExist(err) {
+		t.Fatalf("Unexpected workspace %s after close", w.Path())
+	}
+}
+
+func TestWorkspaceSweep(t *testing.T) {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("cannot spawn process:", err)
+	}
+
+	root := t.TempDir()
+	stale := filepath.Join(root, "job-stale")
+	if err := os.Mkdir(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	pid := strconv.Itoa(cmd.Process.Pid)
+	if err := os.WriteFile(filepath.Join(stale, _workspaceOwnerFile), []byte(pid), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewWorkspaceManager(root, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("Unexpected stale workspace %s", stale)
+	}
+}